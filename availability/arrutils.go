@@ -1,59 +1,51 @@
 package availability
 
-func reduceByFactor(data []byte, factor int, reduceFn func([]byte) byte) []byte {
+func reduceByFactor(data *Bitset, factor int, reduceFn func(data *Bitset, from, to int) byte) *Bitset {
 	//  example
 	//  [a,b,c,d,e,f,g,h,i] factor: 3
 	//  => [fn([a,b,c]), fn([d,e,f]), fn([g,h,i])]
 
-	length := len(data) / factor
-	var reducedData []byte = make([]byte, length)
+	length := data.Len() / factor
+	reducedData := NewBitset(length)
 	for i, j := 0, 0; i < length; i++ {
-		reducedData[i] = reduceFn(data[j : j+factor])
+		if reduceFn(data, j, j+factor) == 1 {
+			reducedData.SetBit(i, 1)
+		}
 		j += factor
 	}
 	return reducedData
 }
 
-func reduceAllOne(data []byte) byte {
-	for _, b := range data {
-		if b != 1 {
-			return 0
-		}
+func reduceAllOne(data *Bitset, from, to int) byte {
+	if data.CountRange(from, to) == to-from {
+		return 1
 	}
-	return 1
+	return 0
 }
 
-func reduceAnyOne(data []byte) byte {
-	for _, b := range data {
-		if b == 1 {
-			return 1
-		}
+func reduceAnyOne(data *Bitset, from, to int) byte {
+	if data.CountRange(from, to) > 0 {
+		return 1
 	}
 	return 0
 }
 
-func reduceMajority(data []byte) byte {
-	sizewin := len(data) / 2
-	count := 0
-	for _, b := range data {
-		if b == 1 {
-			count++
-		}
-	}
-	if count > sizewin {
+func reduceMajority(data *Bitset, from, to int) byte {
+	sizewin := (to - from) / 2
+	if data.CountRange(from, to) > sizewin {
 		return 1
 	}
 	return 0
 }
 
-func multiplyByFactor(data []byte, factor int) []byte {
-	length := len(data) * factor
-	var multipliedData []byte = make([]byte, length)
-	j := 0
-	for _, b := range data {
-		for i := 0; i < factor; i++ {
-			multipliedData[j] = b
-			j++
+func multiplyByFactor(data *Bitset, factor int) *Bitset {
+	multipliedData := NewBitset(data.Len() * factor)
+	if factor == 0 {
+		return multipliedData
+	}
+	for i := 0; i < data.Len(); i++ {
+		if data.Bit(i) == 1 {
+			multipliedData.SetRange(i*factor, (i+1)*factor, 1)
 		}
 	}
 	return multipliedData
@@ -4,15 +4,29 @@ import (
 	"time"
 )
 
+type SetListener func(av *Availability, from, to time.Time, value byte)
+
 type Availability struct {
-	internalRes TimeResolution
-	data        *SegmentedVector
+	internalRes  TimeResolution
+	data         *SegmentedVector
+	version      int
+	resource     *Resource
+	history      *history
+	listeners    map[int]SetListener
+	nextListener int
 }
 
 func NewAvailability(res TimeResolution) *Availability {
+	return NewAvailabilityWithOptions(res, VectorOptions{})
+}
+
+func NewAvailabilityWithOptions(res TimeResolution, options VectorOptions) *Availability {
+	if options.SegmentLength == 0 {
+		options.SegmentLength = int(Day / res)
+	}
 	return &Availability{
 		internalRes: res,
-		data:        NewSegmentedVector(int(Day / res)),
+		data:        NewSegmentedVectorWithOptions(options),
 	}
 }
 
@@ -26,12 +40,69 @@ func LoadAvailability(res TimeResolution, data *SegmentedVector) *Availability {
 func (av *Availability) Set(from, to time.Time, value byte) {
 	fromUnit := TimeToUnit(from, av.internalRes)
 	toUnit := TimeToUnit(to, av.internalRes)
-	av.data.Set(fromUnit, toUnit, value)
+	av.set(fromUnit, toUnit, value)
 }
 
 func (av *Availability) SetAt(at time.Time, value byte) {
 	atUnit := TimeToUnit(at, av.internalRes)
-	av.data.Set(atUnit, atUnit+1, value)
+	av.set(atUnit, atUnit+1, value)
+}
+
+func (av *Availability) set(fromUnit, toUnit int, value byte) {
+	if av.history != nil {
+		av.history.record(av.version+1, fromUnit, toUnit, value, av.data.Get(fromUnit, toUnit))
+	}
+	av.data.Set(fromUnit, toUnit, value)
+	av.version++
+	for _, listener := range av.listeners {
+		listener(av, UnitToTime(fromUnit, av.internalRes), UnitToTime(toUnit, av.internalRes), value)
+	}
+}
+
+// OnSet registers a listener that is called after every Set, the returned
+// function removes it again.
+func (av *Availability) OnSet(listener SetListener) func() {
+	if av.listeners == nil {
+		av.listeners = make(map[int]SetListener)
+	}
+	id := av.nextListener
+	av.nextListener++
+	av.listeners[id] = listener
+	return func() {
+		delete(av.listeners, id)
+	}
+}
+
+func (av *Availability) Resolution() TimeResolution {
+	return av.internalRes
+}
+
+func (av *Availability) Options() VectorOptions {
+	return av.data.Options()
+}
+
+func (av *Availability) Version() int {
+	return av.version
+}
+
+// Clone returns a copy of av with the same version and resource, without the
+// listeners and the history of av.
+func (av *Availability) Clone() *Availability {
+	clone := LoadAvailability(av.internalRes, av.data.Clone()).withVersion(av.version)
+	clone.resource = av.resource.copy()
+	return clone
+}
+
+func (av *Availability) SizeInBytes() int {
+	return av.data.SizeInBytes()
+}
+
+func (av *Availability) Compact(retainFrom time.Time, archive func(Segment)) int {
+	freed := av.data.DropDefaultSegments()
+	if !retainFrom.IsZero() {
+		freed += av.data.Trim(TimeToUnit(retainFrom, av.internalRes), archive)
+	}
+	return freed
 }
 
 func (av *Availability) Get(from, to time.Time, res TimeResolution) *AvailabilityResult {
@@ -46,7 +117,7 @@ func (av *Availability) Get(from, to time.Time, res TimeResolution) *Availabilit
 func (av *Availability) getWithLowerResolution(from, to time.Time, res TimeResolution) *AvailabilityResult {
 	fromUnit := TimeToUnit(RoundDown(from, res), av.internalRes)
 	toUnit := TimeToUnit(RoundUp(to, res), av.internalRes)
-	arr := av.data.Get(fromUnit, toUnit)
+	arr := av.data.GetBits(fromUnit, toUnit)
 	factor := int(res / av.internalRes)
 	reducedArr := reduceByFactor(arr, factor, reduceAllOne)
 	return NewAvailabilityResult(res, av.internalRes, reducedArr, RoundDown(from, res))
@@ -55,19 +126,19 @@ func (av *Availability) getWithLowerResolution(from, to time.Time, res TimeResol
 func (av *Availability) getWithHigherResolution(from, to time.Time, res TimeResolution) *AvailabilityResult {
 	fromUnitInternalRes := TimeToUnit(from, av.internalRes)
 	toUnitInternalRes := TimeToUnit(RoundUp(to, av.internalRes), av.internalRes)
-	arr := av.data.Get(fromUnitInternalRes, toUnitInternalRes)
+	arr := av.data.GetBits(fromUnitInternalRes, toUnitInternalRes)
 	factor := int(av.internalRes / res)
 	arrMultiplied := multiplyByFactor(arr, factor)
 	cutoff := TimeToUnit(from, res) - fromUnitInternalRes*factor
 	origlen := TimeToUnit(to, res) - TimeToUnit(from, res)
-	arrTrimmed := arrMultiplied[cutoff : cutoff+origlen]
+	arrTrimmed := arrMultiplied.Slice(cutoff, cutoff+origlen)
 	return NewAvailabilityResult(res, av.internalRes, arrTrimmed, RoundDown(from, av.internalRes))
 }
 
 func (av *Availability) getWithInternalResolution(from, to time.Time, res TimeResolution) *AvailabilityResult {
 	fromUnit := TimeToUnit(from, res)
 	toUnit := TimeToUnit(to, res)
-	arr := av.data.Get(fromUnit, toUnit)
+	arr := av.data.GetBits(fromUnit, toUnit)
 	return NewAvailabilityResult(res, av.internalRes, arr, RoundDown(from, res))
 }
 
@@ -9,22 +9,22 @@ import (
 // helper functions
 
 func TestMultiplyEmptyArrayByFactor(t *testing.T) {
-	result := multiplyByFactor([]byte{}, 5)
+	result := multiplyByFactor(BitsetFromBytes([]byte{}), 5).Bytes()
 	if !bytes.Equal([]byte{}, result) {
 		t.Errorf("should be an empty array\n")
 	}
 }
 
 func TestMultiplyArrayByFactorZero(t *testing.T) {
-	result := multiplyByFactor([]byte{1, 2, 3}, 0)
+	result := multiplyByFactor(BitsetFromBytes([]byte{1, 0, 1}), 0).Bytes()
 	if !bytes.Equal([]byte{}, result) {
 		t.Errorf("should be an empty array\n")
 	}
 }
 
 func TestMultiplyArrayByFactorThree(t *testing.T) {
-	result := multiplyByFactor([]byte{1, 2, 3}, 3)
-	if !bytes.Equal([]byte{1, 1, 1, 2, 2, 2, 3, 3, 3}, result) {
+	result := multiplyByFactor(BitsetFromBytes([]byte{1, 0, 1}), 3).Bytes()
+	if !bytes.Equal([]byte{1, 1, 1, 0, 0, 0, 1, 1, 1}, result) {
 		t.Errorf("should be an empty array\n")
 	}
 }
@@ -121,7 +121,7 @@ func TestGetAvNothingFromEmpty(t *testing.T) {
 	if bitVector == nil {
 		t.Errorf("the bitVector should not be nil")
 	}
-	if len(bitVector.Data) != 0 {
+	if bitVector.Data.Len() != 0 {
 		t.Errorf("the bitVector should have length zero")
 	}
 
@@ -138,8 +138,8 @@ func TestGetAvFromEmpty(t *testing.T) {
 	bitVector := av.Get(t1, t2, Minute5)
 
 	//t
-	if len(bitVector.Data) != 5 {
-		t.Errorf("the bitVector should have length 5, was %d ", len(bitVector.Data))
+	if bitVector.Data.Len() != 5 {
+		t.Errorf("the bitVector should have length 5, was %d ", bitVector.Data.Len())
 	}
 	if bitVector.Any() {
 		t.Errorf("none of the bits should be set")
@@ -160,7 +160,7 @@ func TestGetAvFromBeforeSet(t *testing.T) {
 	bitVector := av.Get(t1, t2, Minute5)
 
 	//t
-	if l := len(bitVector.Data); l != 5 {
+	if l := bitVector.Data.Len(); l != 5 {
 		t.Errorf("the bitVector bitset should have length 5, was %d", l)
 	}
 	if bitVector.Any() {
@@ -182,7 +182,7 @@ func TestGetAvFromAfterSet(t *testing.T) {
 	bitVector := av.Get(t3, t4, Minute5)
 
 	//t
-	if l := len(bitVector.Data); l != 2 {
+	if l := bitVector.Data.Len(); l != 2 {
 		t.Errorf("the bitVector bitset should have length 2, was %d", l)
 	}
 	if bitVector.Any() {
@@ -204,7 +204,7 @@ func TestGetAvFromInsideSet(t *testing.T) {
 	bitVector := av.Get(t2, t3, Minute5)
 
 	//t
-	if l := len(bitVector.Data); l != 6 {
+	if l := bitVector.Data.Len(); l != 6 {
 		t.Errorf("the bitVector should have length 6, was %v", l)
 	}
 	if !bitVector.All() {
@@ -227,7 +227,7 @@ func TestGetAvFromItersectBeforeSet(t *testing.T) {
 	bitVector := av.Get(t1, t3, Minute5)
 
 	//t
-	if l := len(bitVector.Data); l != 9 {
+	if l := bitVector.Data.Len(); l != 9 {
 		t.Errorf("the bitVector should have length 9, was %d \n", l)
 	}
 	if c := bitVector.Count(); c != 6 {
@@ -249,7 +249,7 @@ func TestGetAvWithLowerResolution(t *testing.T) {
 	bitVector := av.Get(t1, t3, Minute15)
 
 	//t
-	if l, exp := len(bitVector.Data), 3; l != exp {
+	if l, exp := bitVector.Data.Len(), 3; l != exp {
 		t.Errorf("the bitVector should have length %d, was %d \n", exp, l)
 	}
 	if c, exp := bitVector.Count(), 2; c != exp {
@@ -271,7 +271,7 @@ func TestGetAvWithHigherResolution(t *testing.T) {
 	bitVector := av.Get(t1, t3, Minute)
 
 	//t
-	if l, exp := len(bitVector.Data), 45; l != exp {
+	if l, exp := bitVector.Data.Len(), 45; l != exp {
 		t.Errorf("the bitVector should have length %d, was %d \n", exp, l)
 	}
 	if c, exp := bitVector.Count(), 30; c != exp {
@@ -293,7 +293,7 @@ func TestGetAvFromItersectAfterSet(t *testing.T) {
 	bitVector := av.Get(t2, t4, Minute5)
 
 	//t
-	if l := len(bitVector.Data); l != 8 {
+	if l := bitVector.Data.Len(); l != 8 {
 		t.Errorf("the bitVector should have length 8, was %d \n", l)
 	}
 	if c := bitVector.Count(); c != 6 {
@@ -351,7 +351,7 @@ func TestSetAvTwoYearsWorkingHours(t *testing.T) {
 
 	bitVector := av.Get(t2, t2.Add(24*time.Hour), Hour)
 	expected := []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0}
-	if d := bitVector.Data; !bytes.Equal(expected, d) {
+	if d := bitVector.Data.Bytes(); !bytes.Equal(expected, d) {
 		t.Errorf("data should be equal to %v, was %v", expected, d)
 	}
 
@@ -371,12 +371,31 @@ func TestSetAvTwoYearsWorkingHoursBackwards(t *testing.T) {
 
 	bitVector := av.Get(t2, t2.Add(24*time.Hour), Hour)
 	expected := []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0}
-	if d := bitVector.Data; !bytes.Equal(expected, d) {
+	if d := bitVector.Data.Bytes(); !bytes.Equal(expected, d) {
 		t.Errorf("data should be equal to %v, was %v", expected, d)
 	}
 
 }
 
+func TestGetAvOpenByDefault(t *testing.T) {
+	// |1...1111111111100001111111111...111|
+	//          |------get-----|
+	t1 := time.Date(1982, 2, 7, 0, 0, 0, 0, time.UTC)
+	av := NewAvailabilityWithOptions(Hour, VectorOptions{SegmentLength: 24 * 7, DefaultValue: 1})
+	av.Set(t1.Add(10*time.Hour), t1.Add(14*time.Hour), 0)
+
+	//w
+	bitVector := av.Get(t1.Add(-48*time.Hour), t1.Add(400*24*time.Hour), Hour)
+
+	//t
+	if c, exp := bitVector.Count(), bitVector.Data.Len()-4; c != exp {
+		t.Errorf("%d of the bits should be set , %d were \n", exp, c)
+	}
+	if o := av.Options(); o.SegmentLength != 24*7 || o.DefaultValue != 1 {
+		t.Errorf("the options should be kept with the availability, were %v", o)
+	}
+}
+
 func BenchmarkSetAvOneDay(b *testing.B) {
 	av := NewAvailability(Minute5)
 	t1 := time.Date(1982, 2, 7, 0, 0, 0, 0, time.UTC)
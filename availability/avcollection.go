@@ -1,21 +1,40 @@
 package availability
 
 import (
+	"errors"
+	"sort"
+	"strings"
+
 	"github.com/HouzuoGuo/tiedot/db"
 )
 
+var ErrVersionConflict = errors.New("version conflict")
+
 type AvailabilityCollection interface {
 	FindAvailabilityById(id string) *Availability
 	SaveAvailability(id string, av *Availability)
+	// CompareAndSave saves av only if the stored version is still version,
+	// 0 for an id that is not stored yet, and fails with ErrVersionConflict
+	// otherwise. Changes have to be made to a Clone of the stored
+	// availability, changes made to it in place are not undone on conflict.
+	CompareAndSave(id string, av *Availability, version int) error
+	DeleteAvailability(id string) bool
+	// List returns up to limit ids with the given prefix in ascending order,
+	// starting after cursor. next is the cursor of the following page or
+	// empty if there is none.
+	List(prefix, cursor string, limit int) (ids []string, next string)
+	Count() int
 }
 
 type MemAvailabilityCollection struct {
-	avMap map[string]*Availability
+	avMap    map[string]*Availability
+	versions map[string]int
 }
 
 func NewAvailabilityCollection() *MemAvailabilityCollection {
 	return &MemAvailabilityCollection{
-		avMap: make(map[string]*Availability),
+		avMap:    make(map[string]*Availability),
+		versions: make(map[string]int),
 	}
 }
 
@@ -28,6 +47,63 @@ func (avc *MemAvailabilityCollection) FindAvailabilityById(id string) *Availabil
 
 func (avc *MemAvailabilityCollection) SaveAvailability(id string, av *Availability) {
 	avc.avMap[id] = av
+	avc.versions[id] = av.version
+}
+
+// CompareAndSave compares with the version av had when it was saved, as the
+// stored availability might have been changed in place since.
+func (avc *MemAvailabilityCollection) CompareAndSave(id string, av *Availability, version int) error {
+	if err := checkVersion(avc.versions[id], version, av); err != nil {
+		return err
+	}
+	avc.SaveAvailability(id, av)
+	return nil
+}
+
+func (avc *MemAvailabilityCollection) DeleteAvailability(id string) bool {
+	_, ok := avc.avMap[id]
+	delete(avc.avMap, id)
+	delete(avc.versions, id)
+	return ok
+}
+
+func (avc *MemAvailabilityCollection) List(prefix, cursor string, limit int) ([]string, string) {
+	return listIds(avc.avMap, prefix, cursor, limit)
+}
+
+func (avc *MemAvailabilityCollection) Count() int {
+	return len(avc.avMap)
+}
+
+func listIds(avMap map[string]*Availability, prefix, cursor string, limit int) ([]string, string) {
+	var ids []string
+	for id := range avMap {
+		if strings.HasPrefix(id, prefix) && id > cursor {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return page(ids, limit)
+}
+
+// checkVersion fails unless stored is version and makes sure the version of
+// av moves past it.
+func checkVersion(stored, version int, av *Availability) error {
+	if stored != version {
+		return ErrVersionConflict
+	}
+	if av.version <= version {
+		av.version = version + 1
+	}
+	return nil
+}
+
+// page cuts ids after limit and returns the cursor of the next page.
+func page(ids []string, limit int) ([]string, string) {
+	if limit <= 0 || len(ids) <= limit {
+		return ids, ""
+	}
+	return ids[:limit], ids[limit-1]
 }
 
 type TiedotAvailabilityCollection struct {
@@ -56,3 +132,23 @@ func (avc *TiedotAvailabilityCollection) FindAvailabilityById(id string) *Availa
 func (avc *TiedotAvailabilityCollection) SaveAvailability(id string, av *Availability) {
 	avc.collection.Insert(nil)
 }
+
+func (avc *TiedotAvailabilityCollection) CompareAndSave(id string, av *Availability, version int) error {
+	if err := checkVersion(0, version, av); err != nil {
+		return err
+	}
+	avc.SaveAvailability(id, av)
+	return nil
+}
+
+func (avc *TiedotAvailabilityCollection) DeleteAvailability(id string) bool {
+	return false
+}
+
+func (avc *TiedotAvailabilityCollection) List(prefix, cursor string, limit int) ([]string, string) {
+	return nil, ""
+}
+
+func (avc *TiedotAvailabilityCollection) Count() int {
+	return 0
+}
@@ -1,7 +1,9 @@
 package availability
 
 import (
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewAvCollectionShouldNotReturnNil(t *testing.T) {
@@ -53,3 +55,79 @@ func TestSuitefunc(t *testing.T) {
 	}
 
 }
+
+func testListAndDelete(t *testing.T, avc AvailabilityCollection) {
+	for _, id := range []string{"room-13", "room-12", "suite-1", "room-14", "room-15"} {
+		avc.SaveAvailability(id, NewAvailability(Hour))
+	}
+
+	ids, next := avc.List("room-", "", 3)
+	if strings.Join(ids, ",") != "room-12,room-13,room-14" || next != "room-14" {
+		t.Errorf("the first page should be room-12,room-13,room-14 and next room-14, was %v and %q", ids, next)
+	}
+	ids, next = avc.List("room-", next, 3)
+	if strings.Join(ids, ",") != "room-15" || next != "" {
+		t.Errorf("the second page should be room-15 and no next, was %v and %q", ids, next)
+	}
+	if ids, _ := avc.List("", "", 0); len(ids) != 5 {
+		t.Errorf("all 5 ids should be listed without a limit, were %v", ids)
+	}
+
+	if !avc.DeleteAvailability("room-13") {
+		t.Errorf("deleting a saved availability should return true")
+	}
+	if avc.DeleteAvailability("room-13") {
+		t.Errorf("deleting an unknown availability should return false")
+	}
+	if avc.FindAvailabilityById("room-13") != nil {
+		t.Errorf("a deleted availability should not be found")
+	}
+	if c := avc.Count(); c != 4 {
+		t.Errorf("the count should be 4, was %d", c)
+	}
+}
+
+func TestMemCollectionListAndDelete(t *testing.T) {
+	testListAndDelete(t, NewAvailabilityCollection())
+}
+
+func testCompareAndSave(t *testing.T, avc AvailabilityCollection) {
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	av := NewAvailability(Hour)
+	if err := avc.CompareAndSave("room-12", av, 0); err != nil {
+		t.Fatalf("creating should not fail, %v", err)
+	}
+	version := av.Version()
+	if version == 0 {
+		t.Errorf("the version should move on save")
+	}
+	if err := avc.CompareAndSave("room-12", NewAvailability(Hour), 0); err != ErrVersionConflict {
+		t.Errorf("creating twice should fail with %v, was %v", ErrVersionConflict, err)
+	}
+
+	//w two admins edit the same version
+	first, second := avc.FindAvailabilityById("room-12").Clone(), avc.FindAvailabilityById("room-12").Clone()
+	first.Set(t1, t1.Add(time.Hour), 1)
+	err1 := avc.CompareAndSave("room-12", first, version)
+	second.Set(t1.Add(time.Hour), t1.Add(2*time.Hour), 1)
+	err2 := avc.CompareAndSave("room-12", second, version)
+
+	//t
+	if err1 != nil {
+		t.Errorf("the first save should not fail, %v", err1)
+	}
+	if err2 != ErrVersionConflict {
+		t.Errorf("the second save should fail with %v, was %v", ErrVersionConflict, err2)
+	}
+	stored := avc.FindAvailabilityById("room-12")
+	if v := stored.Version(); v <= version {
+		t.Errorf("the stored version should be greater than %d, was %d", version, v)
+	}
+	if b := stored.Get(t1, t1.Add(2*time.Hour), Hour).Data.Bytes(); string(b) != "\x01\x00" {
+		t.Errorf("only the first save should be stored, was %v", b)
+	}
+}
+
+func TestMemCollectionCompareAndSave(t *testing.T) {
+	testCompareAndSave(t, NewAvailabilityCollection())
+}
@@ -11,11 +11,11 @@ type AvailabilityResult struct {
 	InternalResolution TimeResolution `json:"internal_resolution"`
 	From               time.Time      `json:"from"`
 	To                 time.Time      `json:"to"`
-	Data               []byte         `json:"available"`
+	Data               *Bitset        `json:"available"`
 }
 
-func NewAvailabilityResult(res, intRes TimeResolution, data []byte, from time.Time) *AvailabilityResult {
-	to := from.Add(time.Duration(len(data)*int(res)) * time.Second)
+func NewAvailabilityResult(res, intRes TimeResolution, data *Bitset, from time.Time) *AvailabilityResult {
+	to := from.Add(time.Duration(data.Len()*int(res)) * time.Second)
 	return &AvailabilityResult{
 		Resolution:         res,
 		InternalResolution: res,
@@ -37,8 +37,8 @@ func (b *AvailabilityResult) String() string {
 	buffer.WriteString(",")
 	buffer.WriteRune('\n')
 	buffer.WriteString("data:[")
-	for i := 0; i < len(b.Data); i++ {
-		if b.Data[i] == 1 {
+	for i := 0; i < b.Data.Len(); i++ {
+		if b.Data.Bit(i) == 1 {
 			buffer.WriteString("1, ")
 		} else {
 			buffer.WriteString("0, ")
@@ -54,9 +54,9 @@ func (b *AvailabilityResult) String() string {
 
 func (bv *AvailabilityResult) MarshalJSON() ([]byte, error) {
 
-	intdata := make([]int, len(bv.Data))
-	for k, v := range bv.Data {
-		intdata[k] = int(v)
+	intdata := make([]int, bv.Data.Len())
+	for k := range intdata {
+		intdata[k] = int(bv.Data.Bit(k))
 	}
 
 	return json.Marshal(struct {
@@ -75,29 +75,13 @@ func (bv *AvailabilityResult) MarshalJSON() ([]byte, error) {
 }
 
 func (bitVector *AvailabilityResult) All() bool {
-	for _, b := range bitVector.Data {
-		if b == 0 {
-			return false
-		}
-	}
-	return true
+	return bitVector.Data.All()
 }
 
 func (bitVector *AvailabilityResult) Any() bool {
-	for _, b := range bitVector.Data {
-		if b == 1 {
-			return true
-		}
-	}
-	return false
+	return bitVector.Data.Any()
 }
 
 func (bitVector *AvailabilityResult) Count() int {
-	count := 0
-	for _, b := range bitVector.Data {
-		if b == 1 {
-			count++
-		}
-	}
-	return count
+	return bitVector.Data.Count()
 }
@@ -0,0 +1,116 @@
+package availability
+
+import (
+	"math/bits"
+)
+
+type Bitset struct {
+	words  []uint64
+	length int
+}
+
+func NewBitset(length int) *Bitset {
+	return &Bitset{
+		words:  make([]uint64, (length+63)/64),
+		length: length,
+	}
+}
+
+func BitsetFromBytes(data []byte) *Bitset {
+	bitset := NewBitset(len(data))
+	for i, b := range data {
+		if b != 0 {
+			bitset.words[i/64] |= 1 << uint(i%64)
+		}
+	}
+	return bitset
+}
+
+func (b *Bitset) Len() int {
+	return b.length
+}
+
+func (b *Bitset) Bit(i int) byte {
+	return byte(b.words[i/64]>>uint(i%64)) & 1
+}
+
+func (b *Bitset) SetBit(i int, value byte) {
+	setBitRange(b.words, i, i+1, value)
+}
+
+func (b *Bitset) SetRange(from, to int, value byte) {
+	setBitRange(b.words, from, to, value)
+}
+
+func (b *Bitset) Bytes() []byte {
+	data := make([]byte, b.length)
+	readBits(b.words, data, 0)
+	return data
+}
+
+func (b *Bitset) Slice(from, to int) *Bitset {
+	slice := NewBitset(to - from)
+	copyBits(slice.words, 0, b.words, from, to-from)
+	return slice
+}
+
+func (b *Bitset) Count() int {
+	return b.CountRange(0, b.length)
+}
+
+func (b *Bitset) CountRange(from, to int) int {
+	count := 0
+	for from < to {
+		n := 64
+		if to-from < n {
+			n = to - from
+		}
+		count += bits.OnesCount64(extractBits(b.words, from, n))
+		from += n
+	}
+	return count
+}
+
+func (b *Bitset) All() bool {
+	full := b.length / 64
+	for _, w := range b.words[:full] {
+		if w != ^uint64(0) {
+			return false
+		}
+	}
+	if rest := b.length % 64; rest != 0 {
+		return b.words[full] == ^uint64(0)>>uint(64-rest)
+	}
+	return true
+}
+
+func (b *Bitset) Any() bool {
+	for _, w := range b.words {
+		if w != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Bitset) Equal(other *Bitset) bool {
+	if b.length != other.length {
+		return false
+	}
+	for i := range b.words {
+		if b.words[i] != other.words[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func BitsetFromWords(words []uint64, length int) *Bitset {
+	bitset := NewBitset(length)
+	copyBits(bitset.words, 0, words, 0, length)
+	return bitset
+}
+
+func (b *Bitset) Words() []uint64 {
+	return b.words
+}
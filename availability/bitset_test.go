@@ -0,0 +1,73 @@
+package availability
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestBitsetFromBytesRoundTrip(t *testing.T) {
+	data := []byte{1, 0, 1, 1, 0, 0, 0, 1}
+
+	result := BitsetFromBytes(data).Bytes()
+
+	if !bytes.Equal(data, result) {
+		t.Errorf("data should be equal to %v, was %v", data, result)
+	}
+}
+
+func TestBitsetAllAnyCount(t *testing.T) {
+	bitset := NewBitset(130)
+
+	if bitset.Any() {
+		t.Errorf("none of the bits should be set")
+	}
+	bitset.SetRange(0, 130, 1)
+	if !bitset.All() {
+		t.Errorf("all of the bits should be set")
+	}
+	bitset.SetBit(129, 0)
+	if bitset.All() {
+		t.Errorf("not all of the bits should be set")
+	}
+	if c := bitset.Count(); c != 129 {
+		t.Errorf("129 of the bits should be set, %d were", c)
+	}
+	if c := bitset.CountRange(60, 70); c != 10 {
+		t.Errorf("10 of the bits should be set, %d were", c)
+	}
+}
+
+func TestBitsetSliceShouldMatchBytes(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	data := make([]byte, 500)
+	for i := range data {
+		data[i] = byte(r.Intn(2))
+	}
+	bitset := BitsetFromBytes(data)
+
+	for n := 0; n < 200; n++ {
+		from := r.Intn(len(data))
+		to := from + r.Intn(len(data)-from+1)
+		if s := bitset.Slice(from, to).Bytes(); !bytes.Equal(data[from:to], s) {
+			t.Fatalf("slice [%d:%d] should be %v, was %v", from, to, data[from:to], s)
+		}
+	}
+}
+
+func TestAvailabilityResultJSONShape(t *testing.T) {
+	t1 := time.Date(1982, 2, 7, 0, 0, 0, 0, time.UTC)
+	result := NewAvailabilityResult(Hour, Hour, BitsetFromBytes([]byte{0, 1, 1}), t1)
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("marshal should not fail, %v", err)
+	}
+
+	expected := `{"resolution":"hour","internal_resolution":"hour","from":"1982-02-07T00:00:00Z","to":"1982-02-07T03:00:00Z","available":[0,1,1]}`
+	if string(data) != expected {
+		t.Errorf("json should be %s, was %s", expected, data)
+	}
+}
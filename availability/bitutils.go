@@ -0,0 +1,96 @@
+package availability
+
+func setBitRange(words []uint64, from, to int, value byte) {
+	for from < to {
+		word, offset := from/64, uint(from%64)
+		if offset == 0 && to-from >= 64 {
+			if value == 1 {
+				words[word] = ^uint64(0)
+			} else {
+				words[word] = 0
+			}
+			from += 64
+			continue
+		}
+		n := 64 - int(offset)
+		if to-from < n {
+			n = to - from
+		}
+		mask := ^uint64(0) >> uint(64-n) << offset
+		if value == 1 {
+			words[word] |= mask
+		} else {
+			words[word] &^= mask
+		}
+		from += n
+	}
+}
+
+func readBits(words []uint64, dst []byte, from int) {
+	for i := 0; i < len(dst); {
+		word, offset := (from+i)/64, uint((from+i)%64)
+		n := 64 - int(offset)
+		if len(dst)-i < n {
+			n = len(dst) - i
+		}
+		var w uint64
+		if word < len(words) {
+			w = words[word] >> offset
+		}
+		switch {
+		case w == 0:
+			fillBytes(dst[i:i+n], 0)
+		case n == 64 && w == ^uint64(0):
+			fillBytes(dst[i:i+n], 1)
+		default:
+			for k := 0; k < n; k++ {
+				dst[i+k] = byte(w>>uint(k)) & 1
+			}
+		}
+		i += n
+	}
+}
+
+func fillBytes(dst []byte, value byte) {
+	for i := range dst {
+		dst[i] = value
+	}
+}
+
+func extractBits(words []uint64, from, n int) uint64 {
+	word, offset := from/64, uint(from%64)
+	var w uint64
+	if word < len(words) {
+		w = words[word] >> offset
+	}
+	if offset != 0 && word+1 < len(words) {
+		w |= words[word+1] << (64 - offset)
+	}
+	if n < 64 {
+		w &= 1<<uint(n) - 1
+	}
+	return w
+}
+
+func depositBits(words []uint64, at, n int, w uint64) {
+	word, offset := at/64, uint(at%64)
+	mask := ^uint64(0)
+	if n < 64 {
+		mask = 1<<uint(n) - 1
+	}
+	words[word] = words[word]&^(mask<<offset) | w<<offset
+	if offset != 0 && int(offset)+n > 64 {
+		words[word+1] = words[word+1]&^(mask>>(64-offset)) | w>>(64-offset)
+	}
+}
+
+func copyBits(dst []uint64, at int, src []uint64, from, n int) {
+	for n > 0 {
+		k := 64
+		if n < k {
+			k = n
+		}
+		depositBits(dst, at, k, extractBits(src, from, k))
+		at, from, n = at+k, from+k, n-k
+	}
+}
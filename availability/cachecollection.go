@@ -0,0 +1,187 @@
+package availability
+
+import (
+	"container/list"
+	"sync"
+)
+
+type CacheMode int
+
+const (
+	WriteThrough CacheMode = iota
+	WriteBack
+)
+
+type CacheStats struct {
+	Hits      int
+	Misses    int
+	Evictions int
+	Size      int
+}
+
+// CachedAvailabilityCollection keeps the most recently used availabilities of
+// a slower collection in memory, evicting the least recently used ones once
+// their SizeInBytes exceeds maxBytes. In WriteBack mode saves only reach the
+// backend on eviction or Flush.
+type CachedAvailabilityCollection struct {
+	mu       sync.Mutex
+	backend  AvailabilityCollection
+	mode     CacheMode
+	maxBytes int
+	entries  map[string]*list.Element
+	lru      *list.List
+	stats    CacheStats
+	// size is the sum of the entry sizes, measured when they were put
+	size int
+}
+
+type cacheEntry struct {
+	id      string
+	av      *Availability
+	version int
+	size    int
+	dirty   bool
+}
+
+func NewCachedAvailabilityCollection(backend AvailabilityCollection, maxBytes int, mode CacheMode) *CachedAvailabilityCollection {
+	return &CachedAvailabilityCollection{
+		backend:  backend,
+		mode:     mode,
+		maxBytes: maxBytes,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+func (cc *CachedAvailabilityCollection) FindAvailabilityById(id string) *Availability {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if element, ok := cc.entries[id]; ok {
+		cc.stats.Hits++
+		cc.lru.MoveToFront(element)
+		return element.Value.(*cacheEntry).av
+	}
+	cc.stats.Misses++
+	av := cc.backend.FindAvailabilityById(id)
+	if av != nil {
+		cc.put(id, av, false)
+	}
+	return av
+}
+
+func (cc *CachedAvailabilityCollection) SaveAvailability(id string, av *Availability) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.mode == WriteThrough {
+		cc.backend.SaveAvailability(id, av)
+	}
+	cc.put(id, av, cc.mode == WriteBack)
+}
+
+// CompareAndSave compares with the version the cached availability had when
+// it was loaded or saved. In WriteThrough mode the backend compares as well.
+func (cc *CachedAvailabilityCollection) CompareAndSave(id string, av *Availability, version int) error {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.mode == WriteThrough {
+		if err := cc.backend.CompareAndSave(id, av, version); err != nil {
+			return err
+		}
+		cc.put(id, av, false)
+		return nil
+	}
+	stored := 0
+	if element, ok := cc.entries[id]; ok {
+		stored = element.Value.(*cacheEntry).version
+	} else if backendAv := cc.backend.FindAvailabilityById(id); backendAv != nil {
+		stored = backendAv.version
+	}
+	if err := checkVersion(stored, version, av); err != nil {
+		return err
+	}
+	cc.put(id, av, true)
+	return nil
+}
+
+func (cc *CachedAvailabilityCollection) DeleteAvailability(id string) bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cached := false
+	if element, ok := cc.entries[id]; ok {
+		cc.lru.Remove(element)
+		delete(cc.entries, id)
+		cc.size -= element.Value.(*cacheEntry).size
+		cached = true
+	}
+	return cc.backend.DeleteAvailability(id) || cached
+}
+
+// List and Count flush first, so the backend knows every id.
+func (cc *CachedAvailabilityCollection) List(prefix, cursor string, limit int) ([]string, string) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.flush()
+	return cc.backend.List(prefix, cursor, limit)
+}
+
+func (cc *CachedAvailabilityCollection) Count() int {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.flush()
+	return cc.backend.Count()
+}
+
+// Flush saves all availabilities changed in WriteBack mode to the backend.
+func (cc *CachedAvailabilityCollection) Flush() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.flush()
+}
+
+func (cc *CachedAvailabilityCollection) flush() {
+	for element := cc.lru.Back(); element != nil; element = element.Prev() {
+		entry := element.Value.(*cacheEntry)
+		if entry.dirty {
+			cc.backend.SaveAvailability(entry.id, entry.av)
+			entry.dirty = false
+		}
+	}
+}
+
+func (cc *CachedAvailabilityCollection) Stats() CacheStats {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	stats := cc.stats
+	stats.Size = cc.size
+	return stats
+}
+
+// put must be called with cc.mu held. Only the size of the entry put is
+// measured again, changes made in place to other cached availabilities are
+// counted once they are saved.
+func (cc *CachedAvailabilityCollection) put(id string, av *Availability, dirty bool) {
+	element, ok := cc.entries[id]
+	if ok {
+		entry := element.Value.(*cacheEntry)
+		entry.av = av
+		entry.version = av.version
+		entry.dirty = entry.dirty || dirty
+		cc.lru.MoveToFront(element)
+	} else {
+		element = cc.lru.PushFront(&cacheEntry{id: id, av: av, version: av.version, dirty: dirty})
+		cc.entries[id] = element
+	}
+	entry := element.Value.(*cacheEntry)
+	cc.size -= entry.size
+	entry.size = av.SizeInBytes()
+	cc.size += entry.size
+	for cc.size > cc.maxBytes && cc.lru.Len() > 0 {
+		entry := cc.lru.Remove(cc.lru.Back()).(*cacheEntry)
+		delete(cc.entries, entry.id)
+		if entry.dirty {
+			cc.backend.SaveAvailability(entry.id, entry.av)
+		}
+		cc.size -= entry.size
+		cc.stats.Evictions++
+	}
+}
@@ -0,0 +1,126 @@
+package availability
+
+import (
+	"testing"
+	"time"
+)
+
+type countingCollection struct {
+	*MemAvailabilityCollection
+	finds int
+	saves int
+}
+
+func (c *countingCollection) FindAvailabilityById(id string) *Availability {
+	c.finds++
+	return c.MemAvailabilityCollection.FindAvailabilityById(id)
+}
+
+func (c *countingCollection) SaveAvailability(id string, av *Availability) {
+	c.saves++
+	c.MemAvailabilityCollection.SaveAvailability(id, av)
+}
+
+// newSizedAvailability returns an availability of days*3 bytes.
+func newSizedAvailability(days int) *Availability {
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	av := NewAvailability(Hour)
+	for i := 0; i < days; i++ {
+		av.Set(t1.Add(time.Duration(i)*24*time.Hour), t1.Add(time.Duration(i)*24*time.Hour+24*time.Hour), 1)
+	}
+	return av
+}
+
+func TestCachedCollectionShouldCountHitsAndMisses(t *testing.T) {
+	backend := &countingCollection{MemAvailabilityCollection: NewAvailabilityCollection()}
+	backend.SaveAvailability("room-12", newSizedAvailability(1))
+	cc := NewCachedAvailabilityCollection(backend, 1000, WriteThrough)
+
+	//w
+	cc.FindAvailabilityById("room-12")
+	cc.FindAvailabilityById("room-12")
+	cc.FindAvailabilityById("room-13")
+
+	//t
+	stats := cc.Stats()
+	if stats.Hits != 1 || stats.Misses != 2 {
+		t.Errorf("there should be 1 hit and 2 misses, were %d and %d", stats.Hits, stats.Misses)
+	}
+	if backend.finds != 2 {
+		t.Errorf("the backend should be asked 2 times, was %d", backend.finds)
+	}
+	if stats.Size != 3 {
+		t.Errorf("the size should be 3, was %d", stats.Size)
+	}
+}
+
+func TestCachedCollectionShouldEvictLeastRecentlyUsed(t *testing.T) {
+	backend := &countingCollection{MemAvailabilityCollection: NewAvailabilityCollection()}
+	cc := NewCachedAvailabilityCollection(backend, 10, WriteThrough)
+
+	//w
+	cc.SaveAvailability("a", newSizedAvailability(1))
+	cc.SaveAvailability("b", newSizedAvailability(1))
+	cc.FindAvailabilityById("a")
+	cc.SaveAvailability("c", newSizedAvailability(2))
+
+	//t
+	stats := cc.Stats()
+	if stats.Evictions != 1 || stats.Size != 9 {
+		t.Errorf("1 availability should be evicted leaving 9 bytes, %d were leaving %d", stats.Evictions, stats.Size)
+	}
+	if _, ok := cc.entries["b"]; ok {
+		t.Errorf("the least recently used availability should be evicted")
+	}
+	if backend.saves != 3 {
+		t.Errorf("all saves should be written through, %d were", backend.saves)
+	}
+}
+
+func TestCachedCollectionShouldKeepSizeOnReplaceAndDelete(t *testing.T) {
+	cc := NewCachedAvailabilityCollection(NewAvailabilityCollection(), 1000, WriteThrough)
+	cc.SaveAvailability("a", newSizedAvailability(1))
+	cc.SaveAvailability("b", newSizedAvailability(2))
+
+	//w
+	cc.SaveAvailability("a", newSizedAvailability(3))
+	cc.DeleteAvailability("b")
+
+	//t
+	if size := cc.Stats().Size; size != 9 {
+		t.Errorf("the size should be 9, was %d", size)
+	}
+}
+
+func TestCachedCollectionWriteBack(t *testing.T) {
+	backend := &countingCollection{MemAvailabilityCollection: NewAvailabilityCollection()}
+	cc := NewCachedAvailabilityCollection(backend, 6, WriteBack)
+
+	//w
+	cc.SaveAvailability("a", newSizedAvailability(1))
+	cc.SaveAvailability("a", newSizedAvailability(1))
+	cc.SaveAvailability("b", newSizedAvailability(1))
+
+	//t
+	if backend.saves != 0 {
+		t.Errorf("nothing should be written before eviction, %d saves were", backend.saves)
+	}
+	cc.SaveAvailability("c", newSizedAvailability(1))
+	if backend.saves != 1 || backend.MemAvailabilityCollection.FindAvailabilityById("a") == nil {
+		t.Errorf("the evicted availability should be written, %d saves were", backend.saves)
+	}
+	cc.Flush()
+	cc.Flush()
+	if backend.saves != 3 {
+		t.Errorf("flush should write the dirty availabilities once, %d saves were", backend.saves)
+	}
+}
+
+func TestCachedCollectionListAndDelete(t *testing.T) {
+	testListAndDelete(t, NewCachedAvailabilityCollection(NewAvailabilityCollection(), 1000, WriteBack))
+}
+
+func TestCachedCollectionCompareAndSave(t *testing.T) {
+	testCompareAndSave(t, NewCachedAvailabilityCollection(NewAvailabilityCollection(), 1000, WriteThrough))
+	testCompareAndSave(t, NewCachedAvailabilityCollection(NewAvailabilityCollection(), 1000, WriteBack))
+}
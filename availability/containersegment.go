@@ -0,0 +1,240 @@
+package availability
+
+import (
+	"bytes"
+	"math/bits"
+	"sort"
+)
+
+// ContainerSegment stores its units either as a sorted list of runs of ones or
+// as a plain bitmap, whichever is smaller, similar to roaring bitmap containers.
+type ContainerSegment struct {
+	start  int
+	length int
+	runs   []run
+	bitmap []uint64
+}
+
+type run struct {
+	from, to int32
+}
+
+const runSizeInBytes = 8
+
+func NewContainerSegment(start, length int) *ContainerSegment {
+	return &ContainerSegment{
+		start:  start,
+		length: length,
+	}
+}
+
+func (cs *ContainerSegment) Start() int {
+	return cs.start
+}
+
+func (cs *ContainerSegment) IsBitmap() bool {
+	return cs.bitmap != nil
+}
+
+func (cs *ContainerSegment) Bit(i int) uint {
+	if cs.bitmap != nil {
+		return uint(cs.bitmap[i/64]>>uint(i%64)) & 1
+	}
+	k := sort.Search(len(cs.runs), func(k int) bool {
+		return int(cs.runs[k].to) > i
+	})
+	if k < len(cs.runs) && int(cs.runs[k].from) <= i {
+		return 1
+	}
+	return 0
+}
+
+func (cs *ContainerSegment) Read(dst *Bitset, at, from, n int) {
+	if cs.bitmap != nil {
+		copyBits(dst.words, at, cs.bitmap, from, n)
+		return
+	}
+	to := from + n
+	dst.SetRange(at, at+n, 0)
+	for _, r := range cs.runs {
+		runFrom, runTo := int(r.from), int(r.to)
+		if runTo <= from || runFrom >= to {
+			continue
+		}
+		if runFrom < from {
+			runFrom = from
+		}
+		if runTo > to {
+			runTo = to
+		}
+		dst.SetRange(at+runFrom-from, at+runTo-from, 1)
+	}
+}
+
+func (cs *ContainerSegment) SetRange(from, to int, value byte) {
+	if from >= to {
+		return
+	}
+	if cs.bitmap != nil {
+		setBitRange(cs.bitmap, from, to, value)
+	} else if value == 1 {
+		cs.addRun(int32(from), int32(to))
+	} else {
+		cs.removeRun(int32(from), int32(to))
+	}
+	cs.optimize()
+}
+
+func (cs *ContainerSegment) addRun(from, to int32) {
+	runs := make([]run, 0, len(cs.runs)+1)
+	merged := run{from, to}
+	inserted := false
+	for _, r := range cs.runs {
+		switch {
+		case r.to < merged.from:
+			runs = append(runs, r)
+		case r.from > merged.to:
+			if !inserted {
+				runs = append(runs, merged)
+				inserted = true
+			}
+			runs = append(runs, r)
+		default:
+			if r.from < merged.from {
+				merged.from = r.from
+			}
+			if r.to > merged.to {
+				merged.to = r.to
+			}
+		}
+	}
+	if !inserted {
+		runs = append(runs, merged)
+	}
+	cs.runs = runs
+}
+
+func (cs *ContainerSegment) removeRun(from, to int32) {
+	runs := make([]run, 0, len(cs.runs)+1)
+	for _, r := range cs.runs {
+		if r.to <= from || r.from >= to {
+			runs = append(runs, r)
+			continue
+		}
+		if r.from < from {
+			runs = append(runs, run{r.from, from})
+		}
+		if r.to > to {
+			runs = append(runs, run{to, r.to})
+		}
+	}
+	cs.runs = runs
+}
+
+func (cs *ContainerSegment) optimize() {
+	bitmapSize := (cs.length + 63) / 64 * 8
+	if cs.bitmap == nil {
+		if len(cs.runs)*runSizeInBytes > bitmapSize {
+			cs.bitmap = make([]uint64, (cs.length+63)/64)
+			for _, r := range cs.runs {
+				setBitRange(cs.bitmap, int(r.from), int(r.to), 1)
+			}
+			cs.runs = nil
+		}
+		return
+	}
+	if countRuns(cs.bitmap)*runSizeInBytes <= bitmapSize/2 {
+		cs.runs = bitmapToRuns(cs.bitmap, cs.length)
+		cs.bitmap = nil
+	}
+}
+
+func (cs *ContainerSegment) Clone() Segment {
+	clone := NewContainerSegment(cs.start, cs.length)
+	if cs.runs != nil {
+		clone.runs = append([]run(nil), cs.runs...)
+	}
+	if cs.bitmap != nil {
+		clone.bitmap = append([]uint64(nil), cs.bitmap...)
+	}
+	return clone
+}
+
+func (cs *ContainerSegment) SizeInBytes() int {
+	if cs.bitmap != nil {
+		return len(cs.bitmap) * 8
+	}
+	return len(cs.runs) * runSizeInBytes
+}
+
+func (cs *ContainerSegment) equal(other *ContainerSegment) bool {
+	if cs.bitmap == nil && other.bitmap == nil {
+		if len(cs.runs) != len(other.runs) {
+			return false
+		}
+		for i := range cs.runs {
+			if cs.runs[i] != other.runs[i] {
+				return false
+			}
+		}
+		return true
+	}
+	if cs.bitmap != nil && other.bitmap != nil {
+		for i := range cs.bitmap {
+			if cs.bitmap[i] != other.bitmap[i] {
+				return false
+			}
+		}
+		return true
+	}
+	for i := 0; i < cs.length; i++ {
+		if cs.Bit(i) != other.Bit(i) {
+			return false
+		}
+	}
+	return true
+}
+
+func (cs *ContainerSegment) String() string {
+	var buffer bytes.Buffer
+	last := cs.length
+	for last > 0 && cs.Bit(last-1) == 0 {
+		last--
+	}
+	for i := 0; i < last; i++ {
+		if cs.Bit(i) == 1 {
+			buffer.WriteByte('1')
+		} else {
+			buffer.WriteByte('0')
+		}
+	}
+	return buffer.String()
+}
+
+func countRuns(words []uint64) int {
+	count := 0
+	var carry uint64
+	for _, w := range words {
+		// a run starts wherever a one follows a zero
+		count += bits.OnesCount64(w &^ (w<<1 | carry))
+		carry = w >> 63
+	}
+	return count
+}
+
+func bitmapToRuns(words []uint64, length int) []run {
+	var runs []run
+	for i := 0; i < length; {
+		if words[i/64]>>uint(i%64)&1 == 0 {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < length && words[j/64]>>uint(j%64)&1 == 1 {
+			j++
+		}
+		runs = append(runs, run{int32(i), int32(j)})
+		i = j
+	}
+	return runs
+}
@@ -0,0 +1,80 @@
+package availability
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestContainerSegmentSetRangeShouldMergeRuns(t *testing.T) {
+	segment := NewContainerSegment(0, 288)
+
+	segment.SetRange(10, 20, 1)
+	segment.SetRange(30, 40, 1)
+	segment.SetRange(20, 30, 1)
+
+	if l := len(segment.runs); l != 1 {
+		t.Errorf("the runs should have been merged into one, were %d", l)
+	}
+	if s := segment.SizeInBytes(); s != runSizeInBytes {
+		t.Errorf("the segment should need %d bytes, needed %d", runSizeInBytes, s)
+	}
+}
+
+func TestContainerSegmentShouldSwitchToBitmapAndBack(t *testing.T) {
+	segment := NewContainerSegment(0, 288)
+
+	for i := 0; i < 288; i += 2 {
+		segment.SetRange(i, i+1, 1)
+	}
+	if !segment.IsBitmap() {
+		t.Errorf("a fragmented segment should be stored as bitmap")
+	}
+
+	segment.SetRange(0, 288, 1)
+	if segment.IsBitmap() {
+		t.Errorf("a full segment should be stored as runs")
+	}
+	if c := segment.Bit(287); c != 1 {
+		t.Errorf("the last bit should be set")
+	}
+}
+
+func TestContainerSegmentShouldMatchBitSegment(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	length := 1440
+	container := NewContainerSegment(0, length)
+	bitSegment := NewBitSegment(0)
+
+	for n := 0; n < 2000; n++ {
+		from := r.Intn(length)
+		to := from + r.Intn(length-from+1)
+		value := byte(r.Intn(2))
+		container.SetRange(from, to, value)
+		bitSegment.SetRange(from, to, value)
+
+		for i := 0; i < length; i++ {
+			if container.Bit(i) != bitSegment.Bit(i) {
+				t.Fatalf("bit %d differs after %d operations", i, n)
+			}
+		}
+	}
+}
+
+func TestSegmentedVectorWithContainerEncoding(t *testing.T) {
+	vector := NewSegmentedVectorWithEncoding(24, ContainerEncoding)
+
+	vector.Set(20, 50, 1)
+	vector.Set(30, 35, 0)
+
+	data := vector.Get(18, 52)
+	for i, b := range data {
+		unit := i + 18
+		exp := byte(0)
+		if unit >= 20 && unit < 50 && !(unit >= 30 && unit < 35) {
+			exp = 1
+		}
+		if b != exp {
+			t.Errorf("unit %d should be %d, was %d", unit, exp, b)
+		}
+	}
+}
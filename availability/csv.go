@@ -0,0 +1,255 @@
+package availability
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const csvDateLayout = "2006-01-02"
+
+type RangeRecord struct {
+	Id    string
+	From  time.Time
+	To    time.Time
+	Value byte
+}
+
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+type RowErrors []*RowError
+
+func (errs RowErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// ReadRangesCSV reads rows of id,from,to,value. Invalid rows are skipped and
+// reported as RowErrors, the valid records are returned in any case.
+func ReadRangesCSV(r io.Reader) ([]RangeRecord, error) {
+	rows, lines, err := readCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	var records []RangeRecord
+	var errs RowErrors
+	for i, row := range rows {
+		if i == 0 && len(row) > 1 && row[1] == "from" {
+			continue
+		}
+		record, err := parseRangeRow(row)
+		if err != nil {
+			errs = append(errs, &RowError{Row: lines[i], Err: err})
+			continue
+		}
+		records = append(records, record)
+	}
+	if len(errs) > 0 {
+		return records, errs
+	}
+	return records, nil
+}
+
+func parseRangeRow(row []string) (RangeRecord, error) {
+	var record RangeRecord
+	if len(row) != 4 {
+		return record, fmt.Errorf("expected 4 fields, got %d", len(row))
+	}
+	if record.Id = strings.TrimSpace(row[0]); record.Id == "" {
+		return record, errors.New("missing id")
+	}
+	var err error
+	if record.From, err = ParseTime(strings.TrimSpace(row[1])); err != nil {
+		return record, fmt.Errorf("invalid from: %v", err)
+	}
+	if record.To, err = ParseTime(strings.TrimSpace(row[2])); err != nil {
+		return record, fmt.Errorf("invalid to: %v", err)
+	}
+	if record.To.Before(record.From) {
+		return record, errors.New("to is before from")
+	}
+	if record.Value, err = parseValue(row[3]); err != nil {
+		return record, err
+	}
+	return record, nil
+}
+
+// ReadGridCSV reads a header of id followed by one date per column and rows of
+// an id followed by 0 or 1 per day. Empty cells are left unchanged.
+func ReadGridCSV(r io.Reader) ([]RangeRecord, error) {
+	rows, lines, err := readCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	days := make([]time.Time, len(rows[0])-1)
+	for i, column := range rows[0][1:] {
+		if days[i], err = time.Parse(csvDateLayout, strings.TrimSpace(column)); err != nil {
+			return nil, RowErrors{{Row: lines[0], Err: fmt.Errorf("invalid date in column %d: %v", i+2, err)}}
+		}
+	}
+
+	var records []RangeRecord
+	var errs RowErrors
+	for i, row := range rows[1:] {
+		rowRecords, err := parseGridRow(row, days)
+		if err != nil {
+			errs = append(errs, &RowError{Row: lines[i+1], Err: err})
+			continue
+		}
+		records = append(records, rowRecords...)
+	}
+	if len(errs) > 0 {
+		return records, errs
+	}
+	return records, nil
+}
+
+func parseGridRow(row []string, days []time.Time) ([]RangeRecord, error) {
+	if len(row) != len(days)+1 {
+		return nil, fmt.Errorf("expected %d fields, got %d", len(days)+1, len(row))
+	}
+	id := strings.TrimSpace(row[0])
+	if id == "" {
+		return nil, errors.New("missing id")
+	}
+	var records []RangeRecord
+	for i, cell := range row[1:] {
+		if strings.TrimSpace(cell) == "" {
+			continue
+		}
+		value, err := parseValue(cell)
+		if err != nil {
+			return nil, fmt.Errorf("column %d: %v", i+2, err)
+		}
+		records = append(records, RangeRecord{Id: id, From: days[i], To: days[i].AddDate(0, 0, 1), Value: value})
+	}
+	return records, nil
+}
+
+// ApplyRanges sets the records on the availabilities of the collection,
+// creating missing ones with the given resolution.
+func ApplyRanges(avc AvailabilityCollection, res TimeResolution, records []RangeRecord) {
+	touched := make(map[string]*Availability)
+	for _, record := range records {
+		av := touched[record.Id]
+		if av == nil {
+			if av = avc.FindAvailabilityById(record.Id); av == nil {
+				av = NewAvailability(res)
+			}
+			touched[record.Id] = av
+		}
+		av.Set(record.From, record.To, record.Value)
+	}
+	for id, av := range touched {
+		avc.SaveAvailability(id, av)
+	}
+}
+
+func WriteRangesCSV(w io.Writer, id string, result *AvailabilityResult) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "from", "to", "value"})
+	step := time.Duration(result.Resolution) * time.Second
+	for i := 0; i < result.Data.Len(); {
+		j := i + 1
+		for j < result.Data.Len() && result.Data.Bit(j) == result.Data.Bit(i) {
+			j++
+		}
+		cw.Write([]string{
+			id,
+			result.From.Add(time.Duration(i) * step).Format(time.RFC3339),
+			result.From.Add(time.Duration(j) * step).Format(time.RFC3339),
+			strconv.Itoa(int(result.Data.Bit(i))),
+		})
+		i = j
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteGridCSV writes one row per id and one column per day. All results must
+// have day resolution and cover the same days.
+func WriteGridCSV(w io.Writer, results map[string]*AvailabilityResult) error {
+	ids := make([]string, 0, len(results))
+	for id := range results {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	first := results[ids[0]]
+	for _, id := range ids {
+		result := results[id]
+		if result.Resolution != Day || !result.From.Equal(first.From) || result.Data.Len() != first.Data.Len() {
+			return fmt.Errorf("result of %q does not cover the same days with day resolution", id)
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	header := []string{"id"}
+	for i := 0; i < first.Data.Len(); i++ {
+		header = append(header, first.From.AddDate(0, 0, i).Format(csvDateLayout))
+	}
+	cw.Write(header)
+	for _, id := range ids {
+		row := []string{id}
+		for i := 0; i < first.Data.Len(); i++ {
+			row = append(row, strconv.Itoa(int(results[id].Data.Bit(i))))
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// readCSV returns the rows and the line each of them starts on, which differs
+// from the row index once comments or blank lines are skipped.
+func readCSV(r io.Reader) ([][]string, []int, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+	var rows [][]string
+	var lines []int
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return rows, lines, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		rows = append(rows, row)
+		lines = append(lines, line)
+	}
+}
+
+func parseValue(s string) (byte, error) {
+	switch strings.TrimSpace(s) {
+	case "0":
+		return 0, nil
+	case "1":
+		return 1, nil
+	}
+	return 0, fmt.Errorf("invalid value %q", s)
+}
@@ -0,0 +1,121 @@
+package availability
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadRangesCSVShouldReportRowErrors(t *testing.T) {
+	input := `id,from,to,value
+room-12,2026-11-01,2026-11-04,1
+room-12,2026-11-02T12:00:00Z,2026-11-03T00:00:00Z,0
+,2026-11-01,2026-11-02,1
+room-13,2026-11-05,2026-11-01,1
+room-13,2026-11-01,2026-11-02,2
+room-13,2026-11-01,2026-11-02
+`
+
+	records, err := ReadRangesCSV(strings.NewReader(input))
+
+	if l := len(records); l != 2 {
+		t.Errorf("2 records should be valid, %d were", l)
+	}
+	errs, ok := err.(RowErrors)
+	if !ok || len(errs) != 4 {
+		t.Fatalf("4 rows should be invalid, error was %v", err)
+	}
+	for i, row := range []int{4, 5, 6, 7} {
+		if errs[i].Row != row {
+			t.Errorf("error %d should be for row %d, was for row %d", i, row, errs[i].Row)
+		}
+	}
+}
+
+func TestCSVRowErrorsShouldCountCommentsAndBlankLines(t *testing.T) {
+	ranges := `# exported 2026-10-19
+id,from,to,value
+
+room-12,2026-11-01,2026-11-04,1
+# closed for renovation
+room-12,2026-11-02,2026-11-03,2
+`
+	grid := `id,2026-11-01,2026-11-02
+# deluxe
+
+room-12,1,0
+room-13,1,x
+`
+
+	_, rangesErr := ReadRangesCSV(strings.NewReader(ranges))
+	_, gridErr := ReadGridCSV(strings.NewReader(grid))
+
+	for name, expected := range map[string]struct {
+		err error
+		row int
+	}{"ranges": {rangesErr, 6}, "grid": {gridErr, 5}} {
+		errs, ok := expected.err.(RowErrors)
+		if !ok || len(errs) != 1 || errs[0].Row != expected.row {
+			t.Errorf("the %s error should be for line %d, was %v", name, expected.row, expected.err)
+		}
+	}
+}
+
+func TestApplyRangesAndWriteRangesCSV(t *testing.T) {
+	records, err := ReadRangesCSV(strings.NewReader("room-12,2026-11-01,2026-11-04,1\nroom-12,2026-11-02T12:00:00Z,2026-11-03T00:00:00Z,0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	avc := NewAvailabilityCollection()
+
+	//w
+	ApplyRanges(avc, Hour, records)
+
+	//t
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	var buffer bytes.Buffer
+	if err := WriteRangesCSV(&buffer, "room-12", avc.FindAvailabilityById("room-12").Get(t1, t1.Add(96*time.Hour), Hour)); err != nil {
+		t.Fatal(err)
+	}
+	expected := `id,from,to,value
+room-12,2026-11-01T00:00:00Z,2026-11-02T12:00:00Z,1
+room-12,2026-11-02T12:00:00Z,2026-11-03T00:00:00Z,0
+room-12,2026-11-03T00:00:00Z,2026-11-04T00:00:00Z,1
+room-12,2026-11-04T00:00:00Z,2026-11-05T00:00:00Z,0
+`
+	if s := buffer.String(); s != expected {
+		t.Errorf("csv should be\n%s\nwas\n%s", expected, s)
+	}
+}
+
+func TestGridCSVRoundTrip(t *testing.T) {
+	input := `id,2026-11-01,2026-11-02,2026-11-03
+room-12,1,0,1
+room-13,0,1,1
+room-14,0,x,1
+`
+	records, err := ReadGridCSV(strings.NewReader(input))
+	if errs, ok := err.(RowErrors); !ok || len(errs) != 1 || errs[0].Row != 4 {
+		t.Errorf("row 4 should be invalid, error was %v", err)
+	}
+	avc := NewAvailabilityCollection()
+	ApplyRanges(avc, Day, records)
+
+	//w
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	results := map[string]*AvailabilityResult{
+		"room-12": avc.FindAvailabilityById("room-12").Get(t1, t1.AddDate(0, 0, 3), Day),
+		"room-13": avc.FindAvailabilityById("room-13").Get(t1, t1.AddDate(0, 0, 3), Day),
+	}
+	var buffer bytes.Buffer
+	err = WriteGridCSV(&buffer, results)
+
+	//t
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := strings.Join(strings.Split(input, "\n")[:3], "\n") + "\n"; buffer.String() != expected {
+		t.Errorf("csv should be\n%s\nwas\n%s", expected, buffer.String())
+	}
+}
@@ -0,0 +1,73 @@
+package availability
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrIncompatibleAvailabilities = errors.New("availabilities have different resolutions or segment lengths")
+
+func Diff(a, b *Availability, from, to time.Time) ([]Change, error) {
+	if a.internalRes != b.internalRes || a.data.segmentLength != b.data.segmentLength {
+		return nil, ErrIncompatibleAvailabilities
+	}
+	fromUnit := TimeToUnit(from, a.internalRes)
+	toUnit := TimeToUnit(RoundUp(to, a.internalRes), a.internalRes)
+	return diffVectors(a.data, b.data, fromUnit, toUnit, a.internalRes), nil
+}
+
+func diffVectors(a, b *SegmentedVector, from, to int, res TimeResolution) []Change {
+	var changes []Change
+	a.load(from, to)
+	b.load(from, to)
+	for start := a.segmentStart(from); start < to; start += a.segmentLength {
+		segmentA, segmentB := a.segments[start], b.segments[start]
+		if segmentA == segmentB && a.defaultValue == b.defaultValue {
+			continue
+		}
+		if a.segmentEqual(a.getOrEmptySegment(start), b.getOrEmptySegment(start)) {
+			continue
+		}
+		segmentFrom, segmentTo := start, start+a.segmentLength
+		if segmentFrom < from {
+			segmentFrom = from
+		}
+		if segmentTo > to {
+			segmentTo = to
+		}
+		changes = diffData(changes, a.Get(segmentFrom, segmentTo), b.Get(segmentFrom, segmentTo), segmentFrom, res)
+	}
+	return changes
+}
+
+func diffData(changes []Change, oldData, newData []byte, offset int, res TimeResolution) []Change {
+	for i := 0; i < len(oldData); {
+		if oldData[i] == newData[i] {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(oldData) && oldData[j] == oldData[i] && newData[j] == newData[i] {
+			j++
+		}
+		changes = appendChange(changes, Change{
+			From: UnitToTime(offset+i, res),
+			To:   UnitToTime(offset+j, res),
+			Old:  oldData[i],
+			New:  newData[i],
+		})
+		i = j
+	}
+	return changes
+}
+
+func appendChange(changes []Change, change Change) []Change {
+	if n := len(changes); n > 0 {
+		last := &changes[n-1]
+		if last.To.Equal(change.From) && last.Old == change.Old && last.New == change.New {
+			last.To = change.To
+			return changes
+		}
+	}
+	return append(changes, change)
+}
@@ -0,0 +1,66 @@
+package availability
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiffOfEqualAvailabilitiesShouldBeEmpty(t *testing.T) {
+	t1 := time.Date(1982, 2, 7, 0, 0, 0, 0, time.UTC)
+	a := NewAvailability(Minute5)
+	b := NewAvailability(Minute5)
+	a.Set(t1, t1.Add(48*time.Hour), 1)
+	b.Set(t1, t1.Add(48*time.Hour), 1)
+
+	//w
+	changes, err := Diff(a, b, t1.Add(-24*time.Hour), t1.Add(72*time.Hour))
+
+	//t
+	if err != nil {
+		t.Fatalf("diff should not fail, %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("there should be no changes, were %v", changes)
+	}
+}
+
+func TestDiffAcrossSegments(t *testing.T) {
+	// a |0000011111|11111111|11000000|
+	// b |0000011111|11100011|11111100|
+	t1 := time.Date(1982, 2, 7, 0, 0, 0, 0, time.UTC)
+	a := NewAvailability(Hour)
+	b := NewAvailability(Hour)
+	a.Set(t1.Add(5*time.Hour), t1.Add(50*time.Hour), 1)
+	b.Set(t1.Add(5*time.Hour), t1.Add(70*time.Hour), 1)
+	b.Set(t1.Add(20*time.Hour), t1.Add(30*time.Hour), 0)
+
+	//w
+	changes, err := Diff(a, b, t1, t1.Add(72*time.Hour))
+
+	//t
+	if err != nil {
+		t.Fatalf("diff should not fail, %v", err)
+	}
+	expected := []Change{
+		{From: t1.Add(20 * time.Hour), To: t1.Add(30 * time.Hour), Old: 1, New: 0},
+		{From: t1.Add(50 * time.Hour), To: t1.Add(70 * time.Hour), Old: 0, New: 1},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("there should be %d changes, were %v", len(expected), changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("change %d should be %v, was %v", i, expected[i], changes[i])
+		}
+	}
+}
+
+func TestDiffWithDifferentResolutionsShouldFail(t *testing.T) {
+	t1 := time.Date(1982, 2, 7, 0, 0, 0, 0, time.UTC)
+
+	_, err := Diff(NewAvailability(Hour), NewAvailability(Minute5), t1, t1.Add(time.Hour))
+
+	if err != ErrIncompatibleAvailabilities {
+		t.Errorf("diff should fail with %v, was %v", ErrIncompatibleAvailabilities, err)
+	}
+}
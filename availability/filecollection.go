@@ -0,0 +1,419 @@
+package availability
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+)
+
+type SyncPolicy int
+
+const (
+	SyncAlways SyncPolicy = iota
+	SyncBatch
+	SyncNever
+)
+
+const (
+	walFileName      = "wal.log"
+	snapshotFileName = "snapshot.json"
+
+	walRecordPut    byte = 'P'
+	walRecordSet    byte = 'S'
+	walRecordDelete byte = 'D'
+	// walRecordResource holds the resource of an availability as JSON
+	walRecordResource byte = 'R'
+
+	walHeaderSize = 8
+)
+
+var ErrCorruptRecord = errors.New("corrupt wal record")
+
+type FileCollectionOptions struct {
+	Sync          SyncPolicy
+	SyncEvery     int
+	SnapshotEvery int
+}
+
+// FileAvailabilityCollection keeps all availabilities in memory and makes
+// them durable with a write-ahead log of every Set, which is folded into a
+// snapshot file every SnapshotEvery records and on Close.
+type FileAvailabilityCollection struct {
+	mu       sync.Mutex
+	dir      string
+	options  FileCollectionOptions
+	avMap    map[string]*Availability
+	versions map[string]int
+	removers map[string]func()
+	// resources are copies of the logged resources, SetResource is not
+	// seen by the OnSet listeners
+	resources map[string]*Resource
+	wal       *os.File
+	records   int
+	unsynced  int
+	err       error
+}
+
+func OpenFileAvailabilityCollection(dir string, options FileCollectionOptions) (*FileAvailabilityCollection, error) {
+	if options.SyncEvery <= 0 {
+		options.SyncEvery = 100
+	}
+	if options.SnapshotEvery <= 0 {
+		options.SnapshotEvery = 10000
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	fc := &FileAvailabilityCollection{
+		dir:       dir,
+		options:   options,
+		avMap:     make(map[string]*Availability),
+		versions:  make(map[string]int),
+		removers:  make(map[string]func()),
+		resources: make(map[string]*Resource),
+	}
+	if err := fc.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := fc.replay(); err != nil {
+		return nil, err
+	}
+	for id, av := range fc.avMap {
+		fc.versions[id] = av.version
+		fc.resources[id] = av.resource.copy()
+		fc.track(id, av)
+	}
+	return fc, nil
+}
+
+func (fc *FileAvailabilityCollection) FindAvailabilityById(id string) *Availability {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.avMap[id]
+}
+
+func (fc *FileAvailabilityCollection) SaveAvailability(id string, av *Availability) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.versions[id] = av.version
+	if fc.avMap[id] != av {
+		fc.put(id, av)
+	} else if !reflect.DeepEqual(fc.resources[id], av.resource) {
+		data, err := json.Marshal(av.resource)
+		if err != nil {
+			fc.setErr(err)
+			return
+		}
+		fc.resources[id] = av.resource.copy()
+		fc.append(encodeWalRecord(walRecordResource, id, data))
+	}
+}
+
+// CompareAndSave compares with the version av had when it was saved, as
+// tracked availabilities are changed in place.
+func (fc *FileAvailabilityCollection) CompareAndSave(id string, av *Availability, version int) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if err := checkVersion(fc.versions[id], version, av); err != nil {
+		return err
+	}
+	fc.versions[id] = av.version
+	// always logged, checkVersion might have moved the version
+	fc.put(id, av)
+	return nil
+}
+
+// put must be called with fc.mu held.
+func (fc *FileAvailabilityCollection) put(id string, av *Availability) {
+	data, err := json.Marshal(av)
+	if err != nil {
+		fc.setErr(err)
+		return
+	}
+	if remove := fc.removers[id]; remove != nil {
+		remove()
+	}
+	// the map is updated first as append might fold it into a snapshot
+	fc.avMap[id] = av
+	fc.resources[id] = av.resource.copy()
+	fc.track(id, av)
+	fc.append(encodeWalRecord(walRecordPut, id, data))
+}
+
+func (fc *FileAvailabilityCollection) DeleteAvailability(id string) bool {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if _, ok := fc.avMap[id]; !ok {
+		return false
+	}
+	fc.removers[id]()
+	delete(fc.removers, id)
+	delete(fc.avMap, id)
+	delete(fc.versions, id)
+	delete(fc.resources, id)
+	fc.append(encodeWalRecord(walRecordDelete, id, nil))
+	return true
+}
+
+func (fc *FileAvailabilityCollection) List(prefix, cursor string, limit int) ([]string, string) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return listIds(fc.avMap, prefix, cursor, limit)
+}
+
+func (fc *FileAvailabilityCollection) Count() int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return len(fc.avMap)
+}
+
+// Err returns the first error that occurred while writing the log.
+func (fc *FileAvailabilityCollection) Err() error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.err
+}
+
+func (fc *FileAvailabilityCollection) Snapshot() error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.snapshot()
+}
+
+func (fc *FileAvailabilityCollection) Close() error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if err := fc.snapshot(); err != nil {
+		return err
+	}
+	for _, remove := range fc.removers {
+		remove()
+	}
+	return fc.wal.Close()
+}
+
+func (fc *FileAvailabilityCollection) track(id string, av *Availability) {
+	fc.removers[id] = av.OnSet(func(av *Availability, from, to time.Time, value byte) {
+		fc.mu.Lock()
+		defer fc.mu.Unlock()
+		payload := make([]byte, 2*binary.MaxVarintLen64+1)
+		n := binary.PutVarint(payload, from.Unix())
+		n += binary.PutVarint(payload[n:], to.Unix())
+		payload[n] = value
+		fc.append(encodeWalRecord(walRecordSet, id, payload[:n+1]))
+	})
+}
+
+// append must be called with fc.mu held.
+func (fc *FileAvailabilityCollection) append(record []byte) {
+	if fc.err != nil {
+		return
+	}
+	if _, err := fc.wal.Write(record); err != nil {
+		fc.setErr(err)
+		return
+	}
+	fc.records++
+	fc.unsynced++
+	if fc.options.Sync == SyncAlways || fc.options.Sync == SyncBatch && fc.unsynced >= fc.options.SyncEvery {
+		if err := fc.wal.Sync(); err != nil {
+			fc.setErr(err)
+			return
+		}
+		fc.unsynced = 0
+	}
+	if fc.records >= fc.options.SnapshotEvery {
+		fc.setErr(fc.snapshot())
+	}
+}
+
+func (fc *FileAvailabilityCollection) setErr(err error) {
+	if fc.err == nil {
+		fc.err = err
+	}
+}
+
+// snapshot must be called with fc.mu held.
+func (fc *FileAvailabilityCollection) snapshot() error {
+	data, err := json.Marshal(fc.avMap)
+	if err != nil {
+		return err
+	}
+	if err := writeFileSync(filepath.Join(fc.dir, snapshotFileName), data); err != nil {
+		return err
+	}
+	if err := fc.wal.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := fc.wal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	fc.records, fc.unsynced = 0, 0
+	return fc.wal.Sync()
+}
+
+func (fc *FileAvailabilityCollection) loadSnapshot() error {
+	data, err := ioutil.ReadFile(filepath.Join(fc.dir, snapshotFileName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &fc.avMap)
+}
+
+// replay applies the log on top of the snapshot. A torn or corrupt record at
+// the end of the log is the result of a crash during append, the log is
+// truncated before it. A corrupt record followed by others fails with
+// ErrCorruptRecord and leaves the log as it is.
+func (fc *FileAvailabilityCollection) replay() error {
+	wal, err := os.OpenFile(filepath.Join(fc.dir, walFileName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(wal)
+	if err != nil {
+		wal.Close()
+		return err
+	}
+
+	offset := 0
+	for offset < len(data) {
+		kind, id, payload, n, err := decodeWalRecord(data[offset:])
+		if err != nil && isTornRecord(data[offset:]) {
+			break
+		}
+		if err != nil {
+			wal.Close()
+			return fmt.Errorf("%w at offset %d", ErrCorruptRecord, offset)
+		}
+		if err := fc.applyWalRecord(kind, id, payload); err != nil {
+			wal.Close()
+			return fmt.Errorf("%w at offset %d: %v", ErrCorruptRecord, offset, err)
+		}
+		offset += n
+		fc.records++
+	}
+	if offset < len(data) {
+		if err := wal.Truncate(int64(offset)); err != nil {
+			wal.Close()
+			return err
+		}
+	}
+	if _, err := wal.Seek(int64(offset), io.SeekStart); err != nil {
+		wal.Close()
+		return err
+	}
+	fc.wal = wal
+	return nil
+}
+
+func (fc *FileAvailabilityCollection) applyWalRecord(kind byte, id string, payload []byte) error {
+	switch kind {
+	case walRecordPut:
+		av := new(Availability)
+		if err := json.Unmarshal(payload, av); err != nil {
+			return err
+		}
+		fc.avMap[id] = av
+	case walRecordSet:
+		av := fc.avMap[id]
+		if av == nil {
+			return ErrCorruptRecord
+		}
+		from, n := binary.Varint(payload)
+		to, m := binary.Varint(payload[n:])
+		if n <= 0 || m <= 0 || len(payload) != n+m+1 {
+			return ErrCorruptRecord
+		}
+		av.Set(time.Unix(from, 0), time.Unix(to, 0), payload[n+m])
+	case walRecordResource:
+		av := fc.avMap[id]
+		if av == nil {
+			return ErrCorruptRecord
+		}
+		var resource *Resource
+		if err := json.Unmarshal(payload, &resource); err != nil {
+			return err
+		}
+		av.resource = resource
+	case walRecordDelete:
+		delete(fc.avMap, id)
+	default:
+		return ErrCorruptRecord
+	}
+	return nil
+}
+
+// encodeWalRecord frames a record as length, crc32 and payload, the payload
+// being kind, id length, id and data.
+func encodeWalRecord(kind byte, id string, data []byte) []byte {
+	var body bytes.Buffer
+	body.WriteByte(kind)
+	var idLen [binary.MaxVarintLen64]byte
+	body.Write(idLen[:binary.PutUvarint(idLen[:], uint64(len(id)))])
+	body.WriteString(id)
+	body.Write(data)
+
+	record := make([]byte, walHeaderSize, walHeaderSize+body.Len())
+	binary.LittleEndian.PutUint32(record, uint32(body.Len()))
+	binary.LittleEndian.PutUint32(record[4:], crc32.ChecksumIEEE(body.Bytes()))
+	return append(record, body.Bytes()...)
+}
+
+func decodeWalRecord(data []byte) (kind byte, id string, payload []byte, n int, err error) {
+	if len(data) < walHeaderSize {
+		return 0, "", nil, 0, ErrCorruptRecord
+	}
+	length := int(binary.LittleEndian.Uint32(data))
+	if length < 1 || len(data) < walHeaderSize+length {
+		return 0, "", nil, 0, ErrCorruptRecord
+	}
+	body := data[walHeaderSize : walHeaderSize+length]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(data[4:]) {
+		return 0, "", nil, 0, ErrCorruptRecord
+	}
+	idLen, m := binary.Uvarint(body[1:])
+	if m <= 0 || uint64(len(body)-1-m) < idLen {
+		return 0, "", nil, 0, ErrCorruptRecord
+	}
+	start := 1 + m
+	return body[0], string(body[start : start+int(idLen)]), body[start+int(idLen):], walHeaderSize + length, nil
+}
+
+// isTornRecord reports whether the record at the start of data reaches the
+// end of the log, as only the last append can be torn by a crash.
+func isTornRecord(data []byte) bool {
+	return len(data) < walHeaderSize || walHeaderSize+int(binary.LittleEndian.Uint32(data)) >= len(data)
+}
+
+func writeFileSync(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,174 @@
+package availability
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestFileCollection(t *testing.T, dir string, options FileCollectionOptions) *FileAvailabilityCollection {
+	fc, err := OpenFileAvailabilityCollection(dir, options)
+	if err != nil {
+		t.Fatalf("opening the collection should not fail, %v", err)
+	}
+	return fc
+}
+
+func TestFileCollectionShouldRecoverFromLog(t *testing.T) {
+	dir := t.TempDir()
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	fc := openTestFileCollection(t, dir, FileCollectionOptions{})
+	av := NewAvailability(Hour)
+	av.Set(t1, t1.Add(10*time.Hour), 1)
+	fc.SaveAvailability("room-12", av)
+	av.Set(t1.Add(2*time.Hour), t1.Add(4*time.Hour), 0)
+	av.SetAt(t1.Add(20*time.Hour), 1)
+
+	//w crash without closing
+	recovered := openTestFileCollection(t, dir, FileCollectionOptions{})
+
+	//t
+	found := recovered.FindAvailabilityById("room-12")
+	if found == nil {
+		t.Fatalf("the availability should have been recovered")
+	}
+	if changes, _ := Diff(av, found, t1, t1.Add(24*time.Hour)); len(changes) != 0 {
+		t.Errorf("the recovered availability should be equal, changes were %v", changes)
+	}
+	if v := found.Version(); v != av.Version() {
+		t.Errorf("the version should be %d, was %d", av.Version(), v)
+	}
+}
+
+func TestFileCollectionShouldTruncateTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	fc := openTestFileCollection(t, dir, FileCollectionOptions{Sync: SyncNever})
+	av := NewAvailability(Hour)
+	fc.SaveAvailability("room-12", av)
+	av.Set(t1, t1.Add(10*time.Hour), 1)
+	av.Set(t1.Add(2*time.Hour), t1.Add(4*time.Hour), 0)
+
+	walPath := filepath.Join(dir, walFileName)
+	info, err := os.Stat(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// cut the last record in half
+	if err := os.Truncate(walPath, info.Size()-5); err != nil {
+		t.Fatal(err)
+	}
+
+	//w
+	recovered := openTestFileCollection(t, dir, FileCollectionOptions{})
+
+	//t
+	found := recovered.FindAvailabilityById("room-12")
+	if c := found.Get(t1, t1.Add(24*time.Hour), Hour).Count(); c != 10 {
+		t.Errorf("only the complete records should be replayed, 10 bits should be set, %d were", c)
+	}
+	found.SetAt(t1.Add(20*time.Hour), 1)
+	again := openTestFileCollection(t, dir, FileCollectionOptions{})
+	if c := again.FindAvailabilityById("room-12").Get(t1, t1.Add(24*time.Hour), Hour).Count(); c != 11 {
+		t.Errorf("records appended after recovery should be replayed, 11 bits should be set, %d were", c)
+	}
+}
+
+func TestFileCollectionShouldStopAtCorruptRecord(t *testing.T) {
+	dir := t.TempDir()
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	fc := openTestFileCollection(t, dir, FileCollectionOptions{})
+	av := NewAvailability(Hour)
+	fc.SaveAvailability("room-12", av)
+	av.Set(t1, t1.Add(10*time.Hour), 1)
+	av.Set(t1.Add(2*time.Hour), t1.Add(4*time.Hour), 0)
+
+	walPath := filepath.Join(dir, walFileName)
+	data, err := os.ReadFile(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err := os.WriteFile(walPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	recovered := openTestFileCollection(t, dir, FileCollectionOptions{})
+
+	if c := recovered.FindAvailabilityById("room-12").Get(t1, t1.Add(24*time.Hour), Hour).Count(); c != 10 {
+		t.Errorf("the corrupt record should be dropped, 10 bits should be set, %d were", c)
+	}
+}
+
+func TestFileCollectionShouldNotTruncateBeforeValidRecords(t *testing.T) {
+	dir := t.TempDir()
+	fc := openTestFileCollection(t, dir, FileCollectionOptions{})
+	for _, id := range []string{"room-12", "room-13", "room-14", "room-15"} {
+		fc.SaveAvailability(id, NewAvailability(Hour))
+	}
+
+	walPath := filepath.Join(dir, walFileName)
+	data, err := os.ReadFile(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[walHeaderSize] ^= 0xff
+	if err := os.WriteFile(walPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	//w
+	_, err = OpenFileAvailabilityCollection(dir, FileCollectionOptions{})
+
+	//t
+	if !errors.Is(err, ErrCorruptRecord) {
+		t.Errorf("the error should be %v, was %v", ErrCorruptRecord, err)
+	}
+	if info, _ := os.Stat(walPath); info.Size() != int64(len(data)) {
+		t.Errorf("the log should be left as it is, %d bytes, was %d", len(data), info.Size())
+	}
+}
+
+func TestFileCollectionSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	fc := openTestFileCollection(t, dir, FileCollectionOptions{Sync: SyncBatch, SnapshotEvery: 3})
+	av := NewAvailability(Hour)
+	fc.SaveAvailability("room-12", av)
+	for i := 0; i < 5; i++ {
+		av.SetAt(t1.Add(time.Duration(i)*time.Hour), 1)
+	}
+
+	if fc.records >= 3 {
+		t.Errorf("the log should have been folded into a snapshot, %d records left", fc.records)
+	}
+	if err := fc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(filepath.Join(dir, walFileName)); info.Size() != 0 {
+		t.Errorf("the log should be empty after close, was %d bytes", info.Size())
+	}
+
+	recovered := openTestFileCollection(t, dir, FileCollectionOptions{})
+	if c := recovered.FindAvailabilityById("room-12").Get(t1, t1.Add(24*time.Hour), Hour).Count(); c != 5 {
+		t.Errorf("5 bits should be set, %d were", c)
+	}
+}
+
+func TestFileCollectionListAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	testListAndDelete(t, openTestFileCollection(t, dir, FileCollectionOptions{}))
+
+	recovered := openTestFileCollection(t, dir, FileCollectionOptions{})
+
+	if recovered.FindAvailabilityById("room-13") != nil || recovered.Count() != 4 {
+		t.Errorf("the delete should be recovered, %d availabilities were", recovered.Count())
+	}
+}
+
+func TestFileCollectionCompareAndSave(t *testing.T) {
+	dir := t.TempDir()
+	testCompareAndSave(t, openTestFileCollection(t, dir, FileCollectionOptions{}))
+}
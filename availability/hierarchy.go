@@ -0,0 +1,253 @@
+package availability
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrDuplicateNode = errors.New("node already exists")
+	ErrUnknownNode   = errors.New("unknown node")
+	ErrNotAGroup     = errors.New("parent is not a group")
+	ErrGroupNotEmpty = errors.New("group has children")
+)
+
+// Rollup derives the value of a group unit from the number of available
+// children.
+type Rollup func(available, children int) byte
+
+func RollupAny(available, children int) byte {
+	if available > 0 {
+		return 1
+	}
+	return 0
+}
+
+func RollupAll(available, children int) byte {
+	if children > 0 && available == children {
+		return 1
+	}
+	return 0
+}
+
+func RollupAtLeast(n int) Rollup {
+	return func(available, children int) byte {
+		if available >= n {
+			return 1
+		}
+		return 0
+	}
+}
+
+// Hierarchy derives the availability of groups, e.g. a property or a room
+// type, from their children. Groups are updated whenever a child is Set.
+type Hierarchy struct {
+	nodes map[string]*hierarchyNode
+	avs   map[*Availability]bool
+}
+
+type hierarchyNode struct {
+	id       string
+	av       *Availability
+	parent   *hierarchyNode
+	rollup   Rollup
+	children []*hierarchyNode
+	remove   func()
+}
+
+func NewHierarchy() *Hierarchy {
+	return &Hierarchy{
+		nodes: make(map[string]*hierarchyNode),
+		avs:   make(map[*Availability]bool),
+	}
+}
+
+// AddGroup adds a group below parent, or a root if parent is empty, and
+// returns its derived availability.
+func (h *Hierarchy) AddGroup(id, parent string, res TimeResolution, rollup Rollup) (*Availability, error) {
+	node := &hierarchyNode{
+		av:     NewAvailability(res),
+		rollup: rollup,
+	}
+	if err := h.add(id, parent, node); err != nil {
+		return nil, err
+	}
+	return node.av, nil
+}
+
+// Add adds av as a child of the group parent.
+func (h *Hierarchy) Add(id, parent string, av *Availability) error {
+	if parent == "" {
+		return ErrUnknownNode
+	}
+	return h.add(id, parent, &hierarchyNode{av: av})
+}
+
+func (h *Hierarchy) Find(id string) *Availability {
+	if node := h.nodes[id]; node != nil {
+		return node.av
+	}
+	return nil
+}
+
+// Remove detaches the node id from the hierarchy and updates its parent. A
+// group can only be removed once it has no children.
+func (h *Hierarchy) Remove(id string) error {
+	node := h.nodes[id]
+	if node == nil {
+		return ErrUnknownNode
+	}
+	if len(node.children) > 0 {
+		return ErrGroupNotEmpty
+	}
+	delete(h.nodes, id)
+	delete(h.avs, node.av)
+	if node.parent == nil {
+		return nil
+	}
+	node.remove()
+	siblings := node.parent.children
+	for i, sibling := range siblings {
+		if sibling == node {
+			node.parent.children = append(siblings[:i:i], siblings[i+1:]...)
+			break
+		}
+	}
+	node.parent.refresh(node)
+	return nil
+}
+
+// Get returns the number of available children of the group id for every
+// unit of res between from and to, like CountAvailable for a collection.
+func (h *Hierarchy) Get(id string, from, to time.Time, res TimeResolution) (*GroupResult, error) {
+	node := h.nodes[id]
+	if node == nil {
+		return nil, ErrUnknownNode
+	}
+	if node.rollup == nil {
+		return nil, ErrNotAGroup
+	}
+	from, to = RoundDown(from, res), RoundUp(to, res)
+	result := &GroupResult{
+		Resolution: res,
+		From:       from,
+		To:         to,
+		Members:    make([]string, len(node.children)),
+		Counts:     node.counts(from, to, res),
+	}
+	for i, child := range node.children {
+		result.Members[i] = child.id
+	}
+	return result, nil
+}
+
+// Counts returns the Counts of Get.
+func (h *Hierarchy) Counts(id string, from, to time.Time, res TimeResolution) ([]int, error) {
+	result, err := h.Get(id, from, to, res)
+	if err != nil {
+		return nil, err
+	}
+	return result.Counts, nil
+}
+
+func (h *Hierarchy) add(id, parent string, node *hierarchyNode) error {
+	if h.nodes[id] != nil || h.avs[node.av] {
+		return ErrDuplicateNode
+	}
+	if parent != "" {
+		node.parent = h.nodes[parent]
+		if node.parent == nil {
+			return ErrUnknownNode
+		}
+		if node.parent.rollup == nil {
+			return ErrNotAGroup
+		}
+	}
+	node.id = id
+	h.nodes[id] = node
+	h.avs[node.av] = true
+	if node.parent == nil {
+		return nil
+	}
+	node.parent.children = append(node.parent.children, node)
+	node.remove = node.av.OnSet(func(av *Availability, from, to time.Time, value byte) {
+		node.parent.update(from, to)
+	})
+	node.parent.refresh(node)
+	return nil
+}
+
+// refresh updates the group n after child has been added or removed, which
+// can change the group wherever either of them is set, and everywhere else
+// if the default value of the group changes.
+func (n *hierarchyNode) refresh(child *hierarchyNode) {
+	from, to, ok := child.av.span()
+	if groupFrom, groupTo, groupOk := n.av.span(); groupOk {
+		if !ok || groupFrom.Before(from) {
+			from = groupFrom
+		}
+		if !ok || groupTo.After(to) {
+			to = groupTo
+		}
+		ok = true
+	}
+	available := 0
+	for _, c := range n.children {
+		available += int(c.av.data.defaultValue)
+	}
+	defaultValue := n.rollup(available, len(n.children))
+	defaultChanged := defaultValue != n.av.data.defaultValue
+	n.av.data.defaultValue = defaultValue
+	if ok {
+		n.update(from, to)
+	}
+	if defaultChanged && n.parent != nil {
+		n.parent.refresh(n)
+	}
+}
+
+// update recomputes the group between from and to and sets the units whose
+// value changed, which in turn updates the parent of the group.
+func (n *hierarchyNode) update(from, to time.Time) {
+	res := n.av.internalRes
+	from, to = RoundDown(from, res), RoundUp(to, res)
+	counts := n.counts(from, to, res)
+	current := n.av.Get(from, to, res).Data
+	for i := 0; i < len(counts); {
+		value := n.rollup(counts[i], len(n.children))
+		j := i + 1
+		for j < len(counts) && n.rollup(counts[j], len(n.children)) == value {
+			j++
+		}
+		if current.CountRange(i, j) != int(value)*(j-i) {
+			n.av.Set(from.Add(time.Duration(i*int(res))*time.Second), from.Add(time.Duration(j*int(res))*time.Second), value)
+		}
+		i = j
+	}
+}
+
+func (n *hierarchyNode) counts(from, to time.Time, res TimeResolution) []int {
+	counts := make([]int, TimeToUnit(to, res)-TimeToUnit(from, res))
+	for _, child := range n.children {
+		data := child.av.Get(from, to, res).Data
+		for i := range counts {
+			counts[i] += int(data.Bit(i))
+		}
+	}
+	return counts
+}
+
+// span returns the range covered by the segments of av, including the ones
+// not loaded from its source yet.
+func (av *Availability) span() (from, to time.Time, ok bool) {
+	for start := range av.data.allSegments() {
+		if !ok || start < TimeToUnit(from, av.internalRes) {
+			from = UnitToTime(start, av.internalRes)
+		}
+		if end := start + av.data.segmentLength; !ok || end > TimeToUnit(to, av.internalRes) {
+			to = UnitToTime(end, av.internalRes)
+		}
+		ok = true
+	}
+	return from, to, ok
+}
@@ -0,0 +1,173 @@
+package availability
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHierarchyShouldRollUpChildren(t *testing.T) {
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	h := NewHierarchy()
+	property, _ := h.AddGroup("hotel-1", "", Day, RollupAny)
+	deluxe, _ := h.AddGroup("deluxe", "hotel-1", Day, RollupAtLeast(2))
+	standard, _ := h.AddGroup("standard", "hotel-1", Day, RollupAll)
+	rooms := make([]*Availability, 4)
+	for i := range rooms {
+		rooms[i] = NewAvailability(Hour)
+		rooms[i].Set(t1, t1.Add(4*day), 1)
+	}
+	h.Add("room-1", "deluxe", rooms[0])
+	h.Add("room-2", "deluxe", rooms[1])
+	h.Add("room-3", "standard", rooms[2])
+	h.Add("room-4", "standard", rooms[3])
+
+	//w
+	rooms[0].Set(t1.Add(day), t1.Add(2*day), 0)
+	rooms[2].Set(t1.Add(day+12*time.Hour), t1.Add(day+13*time.Hour), 0)
+	rooms[3].Set(t1.Add(2*day), t1.Add(3*day), 0)
+
+	//t
+	if b := deluxe.Get(t1, t1.Add(4*day), Day).Data.Bytes(); string(b) != "\x01\x00\x01\x01" {
+		t.Errorf("deluxe should be 1011, was %v", b)
+	}
+	if b := standard.Get(t1, t1.Add(4*day), Day).Data.Bytes(); string(b) != "\x01\x00\x00\x01" {
+		t.Errorf("standard should be 1001, was %v", b)
+	}
+	if b := property.Get(t1, t1.Add(4*day), Day).Data.Bytes(); string(b) != "\x01\x00\x01\x01" {
+		t.Errorf("the property should be 1011, was %v", b)
+	}
+	counts, err := h.Counts("standard", t1, t1.Add(4*day), Day)
+	if err != nil || len(counts) != 4 || counts[0] != 2 || counts[1] != 1 || counts[2] != 1 || counts[3] != 2 {
+		t.Errorf("the standard counts should be [2 1 1 2], were %v", counts)
+	}
+}
+
+func TestHierarchyShouldUpdateGroupWhenChildIsAdded(t *testing.T) {
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	h := NewHierarchy()
+	group, _ := h.AddGroup("deluxe", "", Hour, RollupAll)
+	first := NewAvailability(Hour)
+	first.Set(t1, t1.Add(10*time.Hour), 1)
+	h.Add("room-1", "deluxe", first)
+	version := group.Version()
+
+	//w
+	h.Add("room-2", "deluxe", NewAvailability(Hour))
+
+	//t
+	if c := group.Get(t1, t1.Add(10*time.Hour), Hour).Data.Count(); c != 0 {
+		t.Errorf("no hour should be available in all rooms, %d were", c)
+	}
+	if group.Version() != version+1 {
+		t.Errorf("the group should be set once, version was %d", group.Version())
+	}
+}
+
+func TestHierarchyErrors(t *testing.T) {
+	h := NewHierarchy()
+	h.AddGroup("hotel-1", "", Day, RollupAny)
+	h.Add("room-1", "hotel-1", NewAvailability(Hour))
+
+	if _, err := h.AddGroup("hotel-1", "", Day, RollupAny); err != ErrDuplicateNode {
+		t.Errorf("the error should be %v, was %v", ErrDuplicateNode, err)
+	}
+	if err := h.Add("room-2", "hotel-2", NewAvailability(Hour)); err != ErrUnknownNode {
+		t.Errorf("the error should be %v, was %v", ErrUnknownNode, err)
+	}
+	if err := h.Add("room-2", "room-1", NewAvailability(Hour)); err != ErrNotAGroup {
+		t.Errorf("the error should be %v, was %v", ErrNotAGroup, err)
+	}
+	if _, err := h.Counts("room-1", time.Now(), time.Now(), Day); err != ErrNotAGroup {
+		t.Errorf("the error should be %v, was %v", ErrNotAGroup, err)
+	}
+}
+
+func TestHierarchyRemoveShouldDetachChild(t *testing.T) {
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	h := NewHierarchy()
+	group, _ := h.AddGroup("deluxe", "", Hour, RollupAll)
+	first, second := NewAvailability(Hour), NewAvailability(Hour)
+	first.Set(t1, t1.Add(10*time.Hour), 1)
+	h.Add("room-1", "deluxe", first)
+	h.Add("room-2", "deluxe", second)
+
+	//w
+	err := h.Remove("room-2")
+	second.Set(t1, t1.Add(10*time.Hour), 0)
+
+	//t
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := group.Get(t1, t1.Add(10*time.Hour), Hour).Data.Count(); c != 10 {
+		t.Errorf("10 hours should be available in all remaining rooms, %d were", c)
+	}
+	if len(second.listeners) != 0 {
+		t.Errorf("the listener of the removed room should be detached")
+	}
+	result, _ := h.Get("deluxe", t1, t1.Add(2*time.Hour), Hour)
+	if len(result.Members) != 1 || result.Members[0] != "room-1" || len(result.Counts) != 2 || result.Counts[0] != 1 {
+		t.Errorf("room-1 should be the only member with count 1, was %v and %v", result.Members, result.Counts)
+	}
+	if err := h.Remove("deluxe"); err != ErrGroupNotEmpty {
+		t.Errorf("the error should be %v, was %v", ErrGroupNotEmpty, err)
+	}
+	if err := h.Remove("room-2"); err != ErrUnknownNode {
+		t.Errorf("the error should be %v, was %v", ErrUnknownNode, err)
+	}
+}
+
+func TestHierarchyShouldNotAddAvailabilityTwice(t *testing.T) {
+	h := NewHierarchy()
+	h.AddGroup("deluxe", "", Hour, RollupAll)
+	room := NewAvailability(Hour)
+	h.Add("room-1", "deluxe", room)
+
+	if err := h.Add("room-2", "deluxe", room); err != ErrDuplicateNode {
+		t.Errorf("the error should be %v, was %v", ErrDuplicateNode, err)
+	}
+	if len(room.listeners) != 1 {
+		t.Errorf("the room should be listened to once, was %d times", len(room.listeners))
+	}
+}
+
+func TestHierarchyShouldRollUpDefaultValues(t *testing.T) {
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	h := NewHierarchy()
+	property, _ := h.AddGroup("hotel-1", "", Day, RollupAny)
+	group, _ := h.AddGroup("deluxe", "hotel-1", Hour, RollupAll)
+	open := NewAvailabilityWithOptions(Hour, VectorOptions{DefaultValue: 1})
+	open.Set(t1, t1.Add(2*time.Hour), 0)
+
+	//w
+	h.Add("room-1", "deluxe", open)
+
+	//t
+	if c := group.Get(t1, t1.Add(4*time.Hour), Hour).Data.Count(); c != 2 {
+		t.Errorf("2 hours should be available, %d were", c)
+	}
+	if c := group.Get(t1.Add(100*24*time.Hour), t1.Add(101*24*time.Hour), Hour).Data.Count(); c != 24 {
+		t.Errorf("hours after the set ones should be available, %d were", c)
+	}
+	if c := property.Get(t1.Add(100*24*time.Hour), t1.Add(101*24*time.Hour), Day).Data.Count(); c != 1 {
+		t.Errorf("the property should be available after the set hours, %d days were", c)
+	}
+}
+
+func TestHierarchySpanShouldIncludeUncachedSegments(t *testing.T) {
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	source := &memSegmentSource{segments: make(map[int][]byte)}
+	lazy := NewAvailabilityWithSource(Hour, VectorOptions{}, source, 2)
+	lazy.Set(t1, t1.Add(5*24*time.Hour), 1)
+	h := NewHierarchy()
+	group, _ := h.AddGroup("deluxe", "", Day, RollupAny)
+
+	//w
+	h.Add("room-1", "deluxe", lazy)
+
+	//t
+	if c := group.Get(t1, t1.Add(5*24*time.Hour), Day).Data.Count(); c != 5 {
+		t.Errorf("5 days should be available, %d were", c)
+	}
+}
@@ -0,0 +1,161 @@
+package availability
+
+import (
+	"sort"
+	"time"
+)
+
+type Revision struct {
+	Version  int
+	Time     time.Time
+	From     time.Time
+	To       time.Time
+	Value    byte
+	fromUnit int
+	toUnit   int
+	previous []byte
+}
+
+type Change struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	Old  byte      `json:"old"`
+	New  byte      `json:"new"`
+}
+
+type history struct {
+	since       time.Time
+	baseVersion int
+	revisions   []*Revision
+	res         TimeResolution
+}
+
+func (h *history) record(version, fromUnit, toUnit int, value byte, previous []byte) {
+	h.revisions = append(h.revisions, &Revision{
+		Version:  version,
+		Time:     now(),
+		From:     UnitToTime(fromUnit, h.res),
+		To:       UnitToTime(toUnit, h.res),
+		Value:    value,
+		fromUnit: fromUnit,
+		toUnit:   toUnit,
+		previous: previous,
+	})
+}
+
+func (h *history) versionAt(t time.Time) int {
+	i := sort.Search(len(h.revisions), func(i int) bool {
+		return h.revisions[i].Time.After(t)
+	})
+	if i == 0 {
+		return h.baseVersion
+	}
+	return h.revisions[i-1].Version
+}
+
+func (av *Availability) EnableHistory() {
+	if av.history != nil {
+		return
+	}
+	av.history = &history{
+		since:       now(),
+		baseVersion: av.version,
+		res:         av.internalRes,
+	}
+}
+
+func (av *Availability) History() []Revision {
+	if av.history == nil {
+		return nil
+	}
+	revisions := make([]Revision, len(av.history.revisions))
+	for i, revision := range av.history.revisions {
+		revisions[i] = *revision
+	}
+	return revisions
+}
+
+func (av *Availability) AsOf(version int) *Availability {
+	if version == av.version {
+		return LoadAvailability(av.internalRes, av.data.Clone()).withVersion(version)
+	}
+	if av.history == nil || version < av.history.baseVersion || version > av.version {
+		return nil
+	}
+	data := av.data.Clone()
+	for i := len(av.history.revisions) - 1; i >= 0; i-- {
+		revision := av.history.revisions[i]
+		if revision.Version <= version {
+			break
+		}
+		data.setData(revision.fromUnit, revision.previous)
+	}
+	return LoadAvailability(av.internalRes, data).withVersion(version)
+}
+
+func (av *Availability) AsOfTime(t time.Time) *Availability {
+	if av.history == nil || t.Before(av.history.since) {
+		return nil
+	}
+	return av.AsOf(av.history.versionAt(t))
+}
+
+func (av *Availability) GetAsOf(version int, from, to time.Time, res TimeResolution) *AvailabilityResult {
+	snapshot := av.AsOf(version)
+	if snapshot == nil {
+		return nil
+	}
+	return snapshot.Get(from, to, res)
+}
+
+func (av *Availability) DiffVersions(v1, v2 int) []Change {
+	if av.history == nil {
+		return nil
+	}
+	if v1 > v2 {
+		v1, v2 = v2, v1
+	}
+	older, newer := av.AsOf(v1), av.AsOf(v2)
+	if older == nil || newer == nil {
+		return nil
+	}
+
+	var touched [][2]int
+	for _, revision := range av.history.revisions {
+		if revision.Version > v1 && revision.Version <= v2 {
+			touched = append(touched, [2]int{revision.fromUnit, revision.toUnit})
+		}
+	}
+
+	var changes []Change
+	for _, interval := range mergeIntervals(touched) {
+		changes = append(changes, diffVectors(older.data, newer.data, interval[0], interval[1], av.internalRes)...)
+	}
+	return changes
+}
+
+func (av *Availability) withVersion(version int) *Availability {
+	av.version = version
+	return av
+}
+
+func mergeIntervals(intervals [][2]int) [][2]int {
+	if len(intervals) == 0 {
+		return nil
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i][0] < intervals[j][0]
+	})
+	merged := [][2]int{intervals[0]}
+	for _, interval := range intervals[1:] {
+		last := &merged[len(merged)-1]
+		if interval[0] <= last[1] {
+			if interval[1] > last[1] {
+				last[1] = interval[1]
+			}
+			continue
+		}
+		merged = append(merged, interval)
+	}
+	return merged
+}
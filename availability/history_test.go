@@ -0,0 +1,122 @@
+package availability
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestVersionShouldIncreaseOnSet(t *testing.T) {
+	av := NewAvailability(Minute5)
+	t1 := time.Date(1982, 2, 7, 0, 0, 0, 0, time.UTC)
+
+	av.Set(t1, t1.Add(time.Hour), 1)
+	av.SetAt(t1, 0)
+
+	if v := av.Version(); v != 2 {
+		t.Errorf("version should be 2, was %d", v)
+	}
+}
+
+func TestAsOfWithoutHistoryShouldBeNil(t *testing.T) {
+	av := NewAvailability(Minute5)
+	t1 := time.Date(1982, 2, 7, 0, 0, 0, 0, time.UTC)
+	av.Set(t1, t1.Add(time.Hour), 1)
+
+	if av.AsOf(0) != nil {
+		t.Errorf("an old version should not be available without history")
+	}
+}
+
+func TestDiffVersionsWithoutHistoryShouldBeNil(t *testing.T) {
+	t1 := time.Date(1982, 2, 7, 0, 0, 0, 0, time.UTC)
+	av := NewAvailability(Hour)
+	av.Set(t1, t1.Add(time.Hour), 1)
+
+	if changes := av.DiffVersions(av.Version(), av.Version()); changes != nil {
+		t.Errorf("the changes should be nil without history, were %v", changes)
+	}
+}
+
+func TestGetAsOfVersion(t *testing.T) {
+	// v1 |0001111111000|
+	// v2 |0001100001000|
+	t1 := time.Date(1982, 2, 7, 0, 0, 0, 0, time.UTC)
+	av := NewAvailability(Hour)
+	av.EnableHistory()
+	av.Set(t1.Add(3*time.Hour), t1.Add(10*time.Hour), 1)
+	av.Set(t1.Add(5*time.Hour), t1.Add(9*time.Hour), 0)
+
+	//w
+	v0 := av.GetAsOf(0, t1, t1.Add(13*time.Hour), Hour)
+	v1 := av.GetAsOf(1, t1, t1.Add(13*time.Hour), Hour)
+	v2 := av.GetAsOf(2, t1, t1.Add(13*time.Hour), Hour)
+
+	//t
+	if v0.Any() {
+		t.Errorf("none of the bits should be set in version 0")
+	}
+	if exp := []byte{0, 0, 0, 1, 1, 1, 1, 1, 1, 1, 0, 0, 0}; !bytes.Equal(exp, v1.Data.Bytes()) {
+		t.Errorf("version 1 should be %v, was %v", exp, v1.Data.Bytes())
+	}
+	if exp := []byte{0, 0, 0, 1, 1, 0, 0, 0, 0, 1, 0, 0, 0}; !bytes.Equal(exp, v2.Data.Bytes()) {
+		t.Errorf("version 2 should be %v, was %v", exp, v2.Data.Bytes())
+	}
+	if av.GetAsOf(3, t1, t1.Add(13*time.Hour), Hour) != nil {
+		t.Errorf("a future version should not be available")
+	}
+}
+
+func TestAsOfTime(t *testing.T) {
+	defer func() { now = time.Now }()
+	t1 := time.Date(1982, 2, 7, 0, 0, 0, 0, time.UTC)
+	clock := time.Date(2014, 3, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+
+	av := NewAvailability(Hour)
+	av.EnableHistory()
+	clock = clock.Add(time.Hour)
+	av.Set(t1, t1.Add(24*time.Hour), 1)
+	clock = clock.Add(24 * time.Hour)
+	av.Set(t1, t1.Add(12*time.Hour), 0)
+
+	//w
+	before := av.AsOfTime(clock.Add(-time.Hour))
+
+	//t
+	if v := before.Version(); v != 1 {
+		t.Errorf("version should be 1, was %d", v)
+	}
+	if c := before.Get(t1, t1.Add(24*time.Hour), Hour).Count(); c != 24 {
+		t.Errorf("24 of the bits should be set, %d were", c)
+	}
+	if av.AsOfTime(clock.Add(-48*time.Hour)) != nil {
+		t.Errorf("there should be no version before the history started")
+	}
+}
+
+func TestDiffVersions(t *testing.T) {
+	t1 := time.Date(1982, 2, 7, 0, 0, 0, 0, time.UTC)
+	av := NewAvailability(Hour)
+	av.EnableHistory()
+	av.Set(t1.Add(3*time.Hour), t1.Add(10*time.Hour), 1)
+	av.Set(t1.Add(5*time.Hour), t1.Add(9*time.Hour), 0)
+	av.Set(t1.Add(6*time.Hour), t1.Add(7*time.Hour), 1)
+
+	//w
+	changes := av.DiffVersions(1, 3)
+
+	//t
+	expected := []Change{
+		{From: t1.Add(5 * time.Hour), To: t1.Add(6 * time.Hour), Old: 1, New: 0},
+		{From: t1.Add(7 * time.Hour), To: t1.Add(9 * time.Hour), Old: 1, New: 0},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("there should be %d changes, were %v", len(expected), changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("change %d should be %v, was %v", i, expected[i], changes[i])
+		}
+	}
+}
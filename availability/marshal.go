@@ -0,0 +1,123 @@
+package availability
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+)
+
+var (
+	ErrInvalidResolution = errors.New("invalid resolution")
+	ErrInvalidVector     = errors.New("invalid segment length, default value, encoding or segment start")
+)
+
+type vectorJSON struct {
+	SegmentLength int             `json:"segment_length"`
+	DefaultValue  byte            `json:"default_value"`
+	Encoding      SegmentEncoding `json:"encoding"`
+	Segments      map[int][]byte  `json:"segments"`
+}
+
+type availabilityJSON struct {
+	Resolution TimeResolution   `json:"resolution"`
+	Version    int              `json:"version"`
+	Resource   *Resource        `json:"resource,omitempty"`
+	Data       *SegmentedVector `json:"data"`
+}
+
+func (sv *SegmentedVector) MarshalJSON() ([]byte, error) {
+	all, err := sv.loadAllSegments()
+	if err != nil {
+		return nil, err
+	}
+	segments := make(map[int][]byte, len(all))
+	for start, segment := range all {
+		segments[start] = sv.segmentBytes(segment)
+	}
+	return json.Marshal(vectorJSON{
+		SegmentLength: sv.segmentLength,
+		DefaultValue:  sv.defaultValue,
+		Encoding:      sv.encoding,
+		Segments:      segments,
+	})
+}
+
+func (sv *SegmentedVector) UnmarshalJSON(data []byte) error {
+	var v vectorJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.SegmentLength <= 0 || v.DefaultValue > 1 || v.Encoding != BitEncoding && v.Encoding != ContainerEncoding {
+		return ErrInvalidVector
+	}
+	vector := NewSegmentedVectorWithOptions(VectorOptions{
+		SegmentLength: v.SegmentLength,
+		DefaultValue:  v.DefaultValue,
+		Encoding:      v.Encoding,
+	})
+	for start, b := range v.Segments {
+		if start != vector.segmentStart(start) {
+			return ErrInvalidVector
+		}
+		vector.storeSegment(vector.segmentFromBytes(start, b))
+	}
+	*sv = *vector
+	return nil
+}
+
+func (av *Availability) MarshalJSON() ([]byte, error) {
+	return json.Marshal(availabilityJSON{
+		Resolution: av.internalRes,
+		Version:    av.version,
+		Resource:   av.resource,
+		Data:       av.data,
+	})
+}
+
+func (av *Availability) UnmarshalJSON(data []byte) error {
+	var v availabilityJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if !v.Resolution.IsValid() {
+		return ErrInvalidResolution
+	}
+	if v.Data == nil {
+		v.Data = NewSegmentedVector(int(Day / v.Resolution))
+	}
+	*av = *LoadAvailability(v.Resolution, v.Data).withVersion(v.Version)
+	av.resource = v.Resource
+	return nil
+}
+
+func (sv *SegmentedVector) segmentBytes(segment Segment) []byte {
+	bitset := NewBitset(sv.segmentLength)
+	segment.Read(bitset, 0, 0, sv.segmentLength)
+	words := bitset.Words()
+	for len(words) > 0 && words[len(words)-1] == 0 {
+		words = words[:len(words)-1]
+	}
+	b := make([]byte, len(words)*8)
+	for i, w := range words {
+		binary.LittleEndian.PutUint64(b[i*8:], w)
+	}
+	return b
+}
+
+func (sv *SegmentedVector) segmentFromBytes(start int, b []byte) Segment {
+	words := make([]uint64, len(b)/8)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint64(b[i*8:])
+	}
+	bitset := BitsetFromWords(words, sv.segmentLength)
+	segment := sv.newSegment(start)
+	for i := 0; i < sv.segmentLength; {
+		j := i + 1
+		for j < sv.segmentLength && bitset.Bit(j) == bitset.Bit(i) {
+			j++
+		}
+		segment.SetRange(i, j, bitset.Bit(i))
+		i = j
+	}
+	return segment
+}
@@ -0,0 +1,49 @@
+package availability
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAvailabilityJSONRoundTrip(t *testing.T) {
+	t1 := time.Date(1982, 2, 7, 0, 0, 0, 0, time.UTC)
+	for _, encoding := range []SegmentEncoding{BitEncoding, ContainerEncoding} {
+		av := NewAvailabilityWithOptions(Minute5, VectorOptions{DefaultValue: 1, Encoding: encoding})
+		av.Set(t1, t1.Add(50*time.Hour), 0)
+		av.Set(t1.Add(3*time.Hour), t1.Add(5*time.Hour), 1)
+
+		data, err := json.Marshal(av)
+		if err != nil {
+			t.Fatal(err)
+		}
+		loaded := new(Availability)
+		if err := json.Unmarshal(data, loaded); err != nil {
+			t.Fatal(err)
+		}
+
+		if loaded.Version() != av.Version() || loaded.Options() != av.Options() || loaded.Resolution() != av.Resolution() {
+			t.Errorf("the loaded availability should have the same version and options")
+		}
+		changes, _ := Diff(av, loaded, t1.Add(-24*time.Hour), t1.Add(96*time.Hour))
+		if len(changes) != 0 {
+			t.Errorf("the loaded availability should be equal, changes were %v", changes)
+		}
+	}
+}
+
+func TestUnmarshalShouldRejectInvalidAvailabilities(t *testing.T) {
+	for data, expected := range map[string]error{
+		`{}`:                   ErrInvalidResolution,
+		`{"resolution": 7}`:    ErrInvalidResolution,
+		`{"resolution": 3600}`: nil,
+		`{"resolution": 3600, "data": {"segment_length": 0}}`:                         ErrInvalidVector,
+		`{"resolution": 3600, "data": {"segment_length": 24, "encoding": 2}}`:         ErrInvalidVector,
+		`{"resolution": 3600, "data": {"segment_length": 24, "default_value": 2}}`:    ErrInvalidVector,
+		`{"resolution": 3600, "data": {"segment_length": 24, "segments": {"5": ""}}}`: ErrInvalidVector,
+	} {
+		if err := json.Unmarshal([]byte(data), new(Availability)); err != expected {
+			t.Errorf("unmarshalling %s should fail with %v, was %v", data, expected, err)
+		}
+	}
+}
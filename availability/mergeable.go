@@ -0,0 +1,286 @@
+package availability
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoClock             = errors.New("mergeable availability has no clock")
+	ErrDefaultAvailability = errors.New("availability defaults to available")
+)
+
+// Timestamp is a hybrid logical clock timestamp, ordered by wall time, then
+// logical counter and then node.
+type Timestamp struct {
+	Wall    int64  `json:"wall"`
+	Logical uint32 `json:"logical"`
+	Node    string `json:"node"`
+}
+
+func (t Timestamp) Less(other Timestamp) bool {
+	if t.Wall != other.Wall {
+		return t.Wall < other.Wall
+	}
+	if t.Logical != other.Logical {
+		return t.Logical < other.Logical
+	}
+	return t.Node < other.Node
+}
+
+// Clock is a hybrid logical clock, its timestamps keep increasing even if
+// the wall clock of the node goes back or lags behind the other nodes.
+type Clock struct {
+	mu   sync.Mutex
+	last Timestamp
+}
+
+func NewClock(node string) *Clock {
+	return &Clock{last: Timestamp{Node: node}}
+}
+
+func (c *Clock) Now() Timestamp {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if wall := now().UnixNano(); wall > c.last.Wall {
+		c.last.Wall, c.last.Logical = wall, 0
+	} else {
+		c.last.Logical++
+	}
+	return c.last
+}
+
+// Observe moves the clock past a timestamp received from another node.
+func (c *Clock) Observe(t Timestamp) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.last.Wall < t.Wall || c.last.Wall == t.Wall && c.last.Logical < t.Logical {
+		c.last.Wall, c.last.Logical = t.Wall, t.Logical
+	}
+}
+
+// MergeableAvailability remembers for every unit the timestamp of the last
+// write, so replicas edited independently converge with Merge, the last
+// writer winning per unit.
+type MergeableAvailability struct {
+	res   TimeResolution
+	clock *Clock
+	runs  []lwwRun
+}
+
+// lwwRun is a range of units written by the same write, runs are sorted and
+// adjacent runs differ in value or timestamp.
+type lwwRun struct {
+	from, to int
+	value    byte
+	ts       Timestamp
+}
+
+type lwwRunJSON struct {
+	From  int       `json:"from"`
+	To    int       `json:"to"`
+	Value byte      `json:"value"`
+	Time  Timestamp `json:"time"`
+}
+
+type mergeableJSON struct {
+	Resolution TimeResolution `json:"resolution"`
+	Runs       []lwwRunJSON   `json:"runs"`
+}
+
+// NewMergeableAvailability returns an empty replica. Replicas without a clock,
+// e.g. ones received from another node, can be merged but not Set.
+func NewMergeableAvailability(res TimeResolution, clock *Clock) *MergeableAvailability {
+	return &MergeableAvailability{
+		res:   res,
+		clock: clock,
+	}
+}
+
+// NewMergeableFromAvailability converts the units of av into a replica, all
+// of them written at the same timestamp of clock. Units outside the segments
+// of av are not written, so av must default to unavailable.
+func NewMergeableFromAvailability(av *Availability, clock *Clock) (*MergeableAvailability, error) {
+	if clock == nil {
+		return nil, ErrNoClock
+	}
+	if av.data.defaultValue != 0 {
+		return nil, ErrDefaultAvailability
+	}
+	segments := av.data.allSegments()
+	starts := make([]int, 0, len(segments))
+	for start := range segments {
+		starts = append(starts, start)
+	}
+	sort.Ints(starts)
+
+	m := NewMergeableAvailability(av.internalRes, clock)
+	ts := clock.Now()
+	for _, start := range starts {
+		for i, value := range av.data.Get(start, start+av.data.segmentLength) {
+			m.runs = appendRun(m.runs, lwwRun{start + i, start + i + 1, value, ts})
+		}
+	}
+	return m, nil
+}
+
+func (m *MergeableAvailability) Set(from, to time.Time, value byte) error {
+	if m.clock == nil {
+		return ErrNoClock
+	}
+	m.set(TimeToUnit(from, m.res), TimeToUnit(to, m.res), value, m.clock.Now())
+	return nil
+}
+
+func (m *MergeableAvailability) set(from, to int, value byte, ts Timestamp) {
+	if from >= to {
+		return
+	}
+	runs := make([]lwwRun, 0, len(m.runs)+2)
+	written := lwwRun{from, to, value, ts}
+	inserted := false
+	for _, r := range m.runs {
+		if r.from >= to && !inserted {
+			runs = appendRun(runs, written)
+			inserted = true
+		}
+		if r.to <= from || r.from >= to {
+			runs = appendRun(runs, r)
+			continue
+		}
+		if r.from < from {
+			runs = appendRun(runs, lwwRun{r.from, from, r.value, r.ts})
+		}
+		if !inserted {
+			runs = appendRun(runs, written)
+			inserted = true
+		}
+		if r.to > to {
+			runs = appendRun(runs, lwwRun{to, r.to, r.value, r.ts})
+		}
+	}
+	if !inserted {
+		runs = appendRun(runs, written)
+	}
+	m.runs = runs
+}
+
+// Merge merges other into m and moves the clock of m past all timestamps of
+// other.
+func (m *MergeableAvailability) Merge(other *MergeableAvailability) error {
+	merged, err := Merge(m, other)
+	if err != nil {
+		return err
+	}
+	m.runs = merged.runs
+	if m.clock != nil {
+		for _, r := range other.runs {
+			m.clock.Observe(r.ts)
+		}
+	}
+	return nil
+}
+
+// Merge returns the per unit last writer wins merge of a and b. It is
+// commutative, associative and idempotent, so replicas converge no matter
+// in which order they are merged. The result uses the clock of a.
+func Merge(a, b *MergeableAvailability) (*MergeableAvailability, error) {
+	if a.res != b.res {
+		return nil, ErrIncompatibleAvailabilities
+	}
+	points := make([]int, 0, 2*(len(a.runs)+len(b.runs)))
+	for _, runs := range [][]lwwRun{a.runs, b.runs} {
+		for _, r := range runs {
+			points = append(points, r.from, r.to)
+		}
+	}
+	sort.Ints(points)
+
+	merged := NewMergeableAvailability(a.res, a.clock)
+	i, j := 0, 0
+	for k := 0; k+1 < len(points); k++ {
+		from, to := points[k], points[k+1]
+		if from == to {
+			continue
+		}
+		for i < len(a.runs) && a.runs[i].to <= from {
+			i++
+		}
+		for j < len(b.runs) && b.runs[j].to <= from {
+			j++
+		}
+		var winner *lwwRun
+		if i < len(a.runs) && a.runs[i].from <= from {
+			winner = &a.runs[i]
+		}
+		if j < len(b.runs) && b.runs[j].from <= from {
+			if winner == nil || winner.ts.Less(b.runs[j].ts) || winner.ts == b.runs[j].ts && winner.value < b.runs[j].value {
+				winner = &b.runs[j]
+			}
+		}
+		if winner != nil {
+			merged.runs = appendRun(merged.runs, lwwRun{from, to, winner.value, winner.ts})
+		}
+	}
+	return merged, nil
+}
+
+// Equal reports whether m and other hold the same writes.
+func (m *MergeableAvailability) Equal(other *MergeableAvailability) bool {
+	if m.res != other.res || len(m.runs) != len(other.runs) {
+		return false
+	}
+	for i := range m.runs {
+		if m.runs[i] != other.runs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Availability returns the current values as a plain Availability.
+func (m *MergeableAvailability) Availability() *Availability {
+	av := NewAvailability(m.res)
+	for _, r := range m.runs {
+		av.data.Set(r.from, r.to, r.value)
+	}
+	return av
+}
+
+func (m *MergeableAvailability) Get(from, to time.Time, res TimeResolution) *AvailabilityResult {
+	return m.Availability().Get(from, to, res)
+}
+
+func (m *MergeableAvailability) MarshalJSON() ([]byte, error) {
+	runs := make([]lwwRunJSON, len(m.runs))
+	for i, r := range m.runs {
+		runs[i] = lwwRunJSON{From: r.from, To: r.to, Value: r.value, Time: r.ts}
+	}
+	return json.Marshal(mergeableJSON{Resolution: m.res, Runs: runs})
+}
+
+// UnmarshalJSON keeps the clock of m, so a replica received from another
+// node can be merged with Merge.
+func (m *MergeableAvailability) UnmarshalJSON(data []byte) error {
+	var v mergeableJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	m.res = v.Resolution
+	m.runs = nil
+	for _, r := range v.Runs {
+		m.set(r.From, r.To, r.Value, r.Time)
+	}
+	return nil
+}
+
+func appendRun(runs []lwwRun, r lwwRun) []lwwRun {
+	if n := len(runs); n > 0 && runs[n-1].to == r.from && runs[n-1].value == r.value && runs[n-1].ts == r.ts {
+		runs[n-1].to = r.to
+		return runs
+	}
+	return append(runs, r)
+}
@@ -0,0 +1,158 @@
+package availability
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// randomReplica is a replica edited offline with a few random writes.
+type randomReplica struct {
+	m *MergeableAvailability
+}
+
+var replicaNodes = []string{"tablet-1", "tablet-2", "tablet-3"}
+
+func (randomReplica) Generate(r *rand.Rand, size int) reflect.Value {
+	m := NewMergeableAvailability(Hour, NewClock(replicaNodes[r.Intn(len(replicaNodes))]))
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	for i := r.Intn(size + 1); i > 0; i-- {
+		from := t1.Add(time.Duration(r.Intn(48)) * time.Hour)
+		m.Set(from, from.Add(time.Duration(1+r.Intn(12))*time.Hour), byte(r.Intn(2)))
+	}
+	return reflect.ValueOf(randomReplica{m})
+}
+
+// withCoarseClock makes the wall clock tick rarely, so writes of different
+// nodes share wall times and are ordered by the logical counter and node.
+func withCoarseClock(t *testing.T) {
+	start := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	calls := 0
+	now = func() time.Time {
+		calls++
+		return start.Add(time.Duration(calls/7) * time.Second)
+	}
+	t.Cleanup(func() { now = time.Now })
+}
+
+func mustMerge(a, b *MergeableAvailability) *MergeableAvailability {
+	merged, err := Merge(a, b)
+	if err != nil {
+		panic(err)
+	}
+	return merged
+}
+
+func TestMergeIsCommutative(t *testing.T) {
+	withCoarseClock(t)
+	commutative := func(a, b randomReplica) bool {
+		return mustMerge(a.m, b.m).Equal(mustMerge(b.m, a.m))
+	}
+	if err := quick.Check(commutative, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMergeIsAssociative(t *testing.T) {
+	withCoarseClock(t)
+	associative := func(a, b, c randomReplica) bool {
+		return mustMerge(mustMerge(a.m, b.m), c.m).Equal(mustMerge(a.m, mustMerge(b.m, c.m)))
+	}
+	if err := quick.Check(associative, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMergeIsIdempotent(t *testing.T) {
+	withCoarseClock(t)
+	idempotent := func(a, b randomReplica) bool {
+		ab := mustMerge(a.m, b.m)
+		return mustMerge(a.m, a.m).Equal(a.m) && mustMerge(ab, b.m).Equal(ab)
+	}
+	if err := quick.Check(idempotent, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMergeShouldKeepLastWriter(t *testing.T) {
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	tablet1 := NewMergeableAvailability(Hour, NewClock("tablet-1"))
+	tablet2 := NewMergeableAvailability(Hour, NewClock("tablet-2"))
+	tablet1.Set(t1, t1.Add(10*time.Hour), 1)
+	tablet2.Merge(tablet1)
+
+	//w both edit offline, tablet-2 later
+	tablet1.Set(t1.Add(2*time.Hour), t1.Add(6*time.Hour), 0)
+	tablet2.Set(t1.Add(4*time.Hour), t1.Add(8*time.Hour), 1)
+	data, _ := json.Marshal(tablet2)
+	received := NewMergeableAvailability(Hour, nil)
+	json.Unmarshal(data, received)
+	tablet1.Merge(received)
+
+	//t
+	if b := tablet1.Get(t1, t1.Add(10*time.Hour), Hour).Data.Bytes(); string(b) != "\x01\x01\x00\x00\x01\x01\x01\x01\x01\x01" {
+		t.Errorf("the hours should be 1100111111, were %v", b)
+	}
+	if !received.Equal(tablet2) {
+		t.Errorf("the replica should survive JSON")
+	}
+	if err := tablet1.Merge(NewMergeableAvailability(Day, nil)); err != ErrIncompatibleAvailabilities {
+		t.Errorf("the error should be %v, was %v", ErrIncompatibleAvailabilities, err)
+	}
+}
+
+func TestMergeableWithoutClockShouldNotBeSet(t *testing.T) {
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	received := new(MergeableAvailability)
+	json.Unmarshal([]byte(`{"resolution":3600,"runs":[]}`), received)
+
+	for _, m := range []*MergeableAvailability{NewMergeableAvailability(Hour, nil), received} {
+		if err := m.Set(t1, t1.Add(time.Hour), 1); err != ErrNoClock {
+			t.Errorf("the error should be %v, was %v", ErrNoClock, err)
+		}
+	}
+}
+
+func TestNewMergeableFromAvailability(t *testing.T) {
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	av := NewAvailability(Hour)
+	av.Set(t1.Add(2*time.Hour), t1.Add(30*time.Hour), 1)
+	clock := NewClock("tablet-1")
+
+	//w
+	m, err := NewMergeableFromAvailability(av, clock)
+
+	//t
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := Diff(av, m.Availability(), t1.Add(-24*time.Hour), t1.Add(72*time.Hour))
+	if err != nil || len(changes) != 0 {
+		t.Errorf("the replica should equal the availability, changes were %v, %v", changes, err)
+	}
+	if err := m.Set(t1, t1.Add(time.Hour), 1); err != nil {
+		t.Errorf("the replica should be writable, the error was %v", err)
+	}
+	if _, err := NewMergeableFromAvailability(av, nil); err != ErrNoClock {
+		t.Errorf("the error should be %v, was %v", ErrNoClock, err)
+	}
+	open := NewAvailabilityWithOptions(Hour, VectorOptions{DefaultValue: 1})
+	if _, err := NewMergeableFromAvailability(open, clock); err != ErrDefaultAvailability {
+		t.Errorf("the error should be %v, was %v", ErrDefaultAvailability, err)
+	}
+}
+
+func TestClockShouldMovePastObservedTimestamps(t *testing.T) {
+	clock := NewClock("tablet-1")
+	ahead := Timestamp{Wall: time.Now().Add(time.Hour).UnixNano(), Logical: 3, Node: "tablet-2"}
+
+	clock.Observe(ahead)
+	ts := clock.Now()
+
+	if !ahead.Less(ts) || ts.Node != "tablet-1" {
+		t.Errorf("the timestamp %+v should be after %+v", ts, ahead)
+	}
+}
@@ -0,0 +1,169 @@
+package availability
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrNoRate              = errors.New("no rate")
+	ErrIncompatibleRateRes = errors.New("resolution must be a multiple of the rate resolution")
+	ErrNegativeRate        = errors.New("rate must not be negative")
+)
+
+// noRate marks units without a rate, SetRate rejects negative amounts.
+const noRate int64 = -1
+
+// RateCalendar stores a price in minor currency units per time unit, in the
+// same sparse segmented layout as SegmentedVector.
+type RateCalendar struct {
+	res           TimeResolution
+	currency      string
+	segmentLength int
+	segments      map[int][]int64
+}
+
+type Quote struct {
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Currency string    `json:"currency"`
+	Total    int64     `json:"total"`
+}
+
+type PricedBucket struct {
+	From      time.Time `json:"from"`
+	Available byte      `json:"available"`
+	Price     *int64    `json:"price"`
+}
+
+type PricedResult struct {
+	Resolution string         `json:"resolution"`
+	Currency   string         `json:"currency"`
+	From       time.Time      `json:"from"`
+	To         time.Time      `json:"to"`
+	Buckets    []PricedBucket `json:"buckets"`
+}
+
+func NewRateCalendar(res TimeResolution, currency string) *RateCalendar {
+	return &RateCalendar{
+		res:           res,
+		currency:      currency,
+		segmentLength: int(Day / res),
+		segments:      make(map[int][]int64),
+	}
+}
+
+func (rc *RateCalendar) Currency() string {
+	return rc.currency
+}
+
+func (rc *RateCalendar) SetRate(from, to time.Time, amount int64) error {
+	if amount < 0 {
+		return ErrNegativeRate
+	}
+	rc.set(TimeToUnit(from, rc.res), TimeToUnit(to, rc.res), amount)
+	return nil
+}
+
+func (rc *RateCalendar) ClearRate(from, to time.Time) {
+	rc.set(TimeToUnit(from, rc.res), TimeToUnit(to, rc.res), noRate)
+}
+
+func (rc *RateCalendar) RateAt(at time.Time) (int64, bool) {
+	amount := rc.get(TimeToUnit(at, rc.res))
+	return amount, amount != noRate
+}
+
+// Quote sums the rates of all units between from and to, provided av is
+// available for all of them.
+func (rc *RateCalendar) Quote(av *Availability, from, to time.Time) (*Quote, error) {
+	if !av.Get(from, to, rc.res).All() {
+		return nil, ErrNotAvailable
+	}
+	var total int64
+	for unit := TimeToUnit(from, rc.res); unit < TimeToUnit(to, rc.res); unit++ {
+		amount := rc.get(unit)
+		if amount == noRate {
+			return nil, fmt.Errorf("%w at %s", ErrNoRate, UnitToTime(unit, rc.res).Format(time.RFC3339))
+		}
+		total += amount
+	}
+	return &Quote{
+		From:     RoundDown(from, rc.res),
+		To:       RoundDown(to, rc.res),
+		Currency: rc.currency,
+		Total:    total,
+	}, nil
+}
+
+// Get combines the availability of av with the price per bucket, the price of
+// a bucket is the sum of its units and nil if any of them has no rate.
+func (rc *RateCalendar) Get(av *Availability, from, to time.Time, res TimeResolution) (*PricedResult, error) {
+	if res < rc.res || res%rc.res != 0 {
+		return nil, ErrIncompatibleRateRes
+	}
+	result := av.Get(from, to, res)
+	factor := int(res / rc.res)
+	firstUnit := TimeToUnit(result.From, rc.res)
+	buckets := make([]PricedBucket, result.Data.Len())
+	for i := range buckets {
+		buckets[i] = PricedBucket{
+			From:      result.From.Add(time.Duration(i*int(res)) * time.Second),
+			Available: result.Data.Bit(i),
+		}
+		var sum int64
+		priced := true
+		for unit := firstUnit + i*factor; unit < firstUnit+(i+1)*factor; unit++ {
+			amount := rc.get(unit)
+			if amount == noRate {
+				priced = false
+				break
+			}
+			sum += amount
+		}
+		if priced {
+			buckets[i].Price = &sum
+		}
+	}
+	return &PricedResult{
+		Resolution: res.String(),
+		Currency:   rc.currency,
+		From:       result.From,
+		To:         result.To,
+		Buckets:    buckets,
+	}, nil
+}
+
+func (rc *RateCalendar) set(from, to int, amount int64) {
+	for unit := from; unit < to; unit++ {
+		start := rc.segmentStart(unit)
+		segment := rc.segments[start]
+		if segment == nil {
+			if amount == noRate {
+				continue
+			}
+			segment = make([]int64, rc.segmentLength)
+			for i := range segment {
+				segment[i] = noRate
+			}
+			rc.segments[start] = segment
+		}
+		segment[unit-start] = amount
+	}
+}
+
+func (rc *RateCalendar) get(unit int) int64 {
+	start := rc.segmentStart(unit)
+	if segment := rc.segments[start]; segment != nil {
+		return segment[unit-start]
+	}
+	return noRate
+}
+
+func (rc *RateCalendar) segmentStart(unit int) int {
+	if r := unit % rc.segmentLength; r < 0 {
+		return unit - r - rc.segmentLength
+	}
+	return unit - unit%rc.segmentLength
+}
@@ -0,0 +1,91 @@
+package availability
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateAt(t *testing.T) {
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	rc := NewRateCalendar(Day, "EUR")
+	rc.SetRate(t1, t1.AddDate(0, 0, 7), 12000)
+	rc.ClearRate(t1.AddDate(0, 0, 2), t1.AddDate(0, 0, 3))
+
+	if amount, ok := rc.RateAt(t1.Add(5 * time.Hour)); !ok || amount != 12000 {
+		t.Errorf("the rate should be 12000, was %d", amount)
+	}
+	if _, ok := rc.RateAt(t1.AddDate(0, 0, 2)); ok {
+		t.Errorf("there should be no rate on the cleared day")
+	}
+	if _, ok := rc.RateAt(t1.AddDate(0, 0, -1)); ok {
+		t.Errorf("there should be no rate before the first set day")
+	}
+}
+
+func TestSetRateShouldRejectNegativeAmounts(t *testing.T) {
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	rc := NewRateCalendar(Day, "EUR")
+	rc.SetRate(t1, t1.AddDate(0, 0, 2), 12000)
+
+	//w
+	err := rc.SetRate(t1, t1.AddDate(0, 0, 2), -1)
+
+	//t
+	if err != ErrNegativeRate {
+		t.Errorf("the error should be %v, was %v", ErrNegativeRate, err)
+	}
+	if amount, ok := rc.RateAt(t1); !ok || amount != 12000 {
+		t.Errorf("the rate should still be 12000, was %d", amount)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	nov := func(day int) time.Time { return time.Date(2026, 11, day, 0, 0, 0, 0, time.UTC) }
+	av := NewAvailability(Day)
+	av.Set(nov(1), nov(10), 1)
+	av.Set(nov(5), nov(6), 0)
+	rc := NewRateCalendar(Day, "EUR")
+	rc.SetRate(nov(1), nov(8), 10000)
+	rc.SetRate(nov(3), nov(4), 15000)
+
+	quote, err := rc.Quote(av, nov(1), nov(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quote.Total != 35000 || quote.Currency != "EUR" {
+		t.Errorf("the stay should cost 35000 EUR, was %d %s", quote.Total, quote.Currency)
+	}
+	if _, err := rc.Quote(av, nov(4), nov(7)); err != ErrNotAvailable {
+		t.Errorf("a stay over an unavailable night should fail with %v, was %v", ErrNotAvailable, err)
+	}
+	if _, err := rc.Quote(av, nov(7), nov(9)); !errors.Is(err, ErrNoRate) {
+		t.Errorf("a stay over an unpriced night should fail with %v, was %v", ErrNoRate, err)
+	}
+}
+
+func TestPricedResultJSON(t *testing.T) {
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	av := NewAvailability(Hour)
+	av.Set(t1, t1.Add(48*time.Hour), 1)
+	rc := NewRateCalendar(Hour, "EUR")
+	rc.SetRate(t1, t1.Add(24*time.Hour), 500)
+
+	result, err := rc.Get(av, t1, t1.Add(48*time.Hour), Day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"resolution":"day","currency":"EUR","from":"2026-11-01T00:00:00Z","to":"2026-11-03T00:00:00Z","buckets":[{"from":"2026-11-01T00:00:00Z","available":1,"price":12000},{"from":"2026-11-02T00:00:00Z","available":1,"price":null}]}`
+	if string(data) != expected {
+		t.Errorf("json should be %s, was %s", expected, data)
+	}
+	if _, err := rc.Get(av, t1, t1.Add(48*time.Hour), Minute); err != ErrIncompatibleRateRes {
+		t.Errorf("a finer resolution should fail with %v, was %v", ErrIncompatibleRateRes, err)
+	}
+}
@@ -0,0 +1,131 @@
+package availability
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	svgCellSize    = 14
+	svgLabelWidth  = 84
+	svgHeaderSpace = 18
+)
+
+type gridCell struct {
+	count, length int
+}
+
+type gridRow struct {
+	day   time.Time
+	cells []gridCell
+}
+
+// grid lays the result out with one row per day and one cell per hour, or one
+// cell per day for results coarser than an hour.
+func (b *AvailabilityResult) grid() (cellRes TimeResolution, rows []gridRow) {
+	cellRes = Hour
+	if b.Resolution > Hour {
+		cellRes = Day
+	}
+	length := b.Data.Len()
+	for day := RoundDown(b.From, Day); day.Before(b.To); day = day.Add(time.Duration(Day) * time.Second) {
+		row := gridRow{day: day, cells: make([]gridCell, Day/cellRes)}
+		for c := range row.cells {
+			cellFrom := int(day.Unix()-b.From.Unix()) + c*int(cellRes)
+			cellTo := cellFrom + int(cellRes)
+			from, to := floorDiv(cellFrom, int(b.Resolution)), -floorDiv(-cellTo, int(b.Resolution))
+			if from < 0 {
+				from = 0
+			}
+			if to > length {
+				to = length
+			}
+			if from < to {
+				row.cells[c] = gridCell{count: b.Data.CountRange(from, to), length: to - from}
+			}
+		}
+		rows = append(rows, row)
+	}
+	return cellRes, rows
+}
+
+func floorDiv(a, b int) int {
+	if a < 0 {
+		return -((-a + b - 1) / b)
+	}
+	return a / b
+}
+
+func (c gridCell) char() byte {
+	switch {
+	case c.length == 0:
+		return ' '
+	case c.count == c.length:
+		return '#'
+	case c.count > 0:
+		return '+'
+	}
+	return '.'
+}
+
+// RenderASCII writes the result as a grid with days as rows and hours as
+// columns: '#' is available, '.' is unavailable and '+' is partly available.
+func RenderASCII(w io.Writer, result *AvailabilityResult) error {
+	bw := bufio.NewWriter(w)
+	cellRes, rows := result.grid()
+	fmt.Fprintf(bw, "%s - %s, res: %s\n", result.From.Format(time.RFC3339), result.To.Format(time.RFC3339), result.Resolution)
+	if cellRes == Hour {
+		bw.WriteString("            000000000011111111112222\n")
+		bw.WriteString("            012345678901234567890123\n")
+	}
+	for _, row := range rows {
+		bw.WriteString(row.day.Format("2006-01-02"))
+		bw.WriteString("  ")
+		for _, cell := range row.cells {
+			bw.WriteByte(cell.char())
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// RenderSVG writes the result as a heat map using the same grid as RenderASCII,
+// the opacity of a cell is the available fraction of it.
+func RenderSVG(w io.Writer, result *AvailabilityResult) error {
+	bw := bufio.NewWriter(w)
+	cellRes, rows := result.grid()
+	columns := int(Day / cellRes)
+	width := svgLabelWidth + columns*svgCellSize
+	height := svgHeaderSpace + len(rows)*svgCellSize
+
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="monospace" font-size="10">`+"\n", width, height)
+	if cellRes == Hour {
+		for c := 0; c < columns; c += 3 {
+			fmt.Fprintf(bw, `<text x="%d" y="12">%02d</text>`+"\n", svgLabelWidth+c*svgCellSize, c)
+		}
+	}
+	for r, row := range rows {
+		y := svgHeaderSpace + r*svgCellSize
+		fmt.Fprintf(bw, `<text x="0" y="%d">%s</text>`+"\n", y+svgCellSize-3, row.day.Format("2006-01-02"))
+		for c, cell := range row.cells {
+			if cell.length == 0 {
+				continue
+			}
+			fmt.Fprintf(bw, `<rect x="%d" y="%d" width="%d" height="%d" fill="#2e7d32" fill-opacity="%.2f" stroke="#cccccc"><title>%s</title></rect>`+"\n",
+				svgLabelWidth+c*svgCellSize, y, svgCellSize, svgCellSize,
+				float64(cell.count)/float64(cell.length),
+				row.day.Add(time.Duration(c*int(cellRes))*time.Second).Format(time.RFC3339))
+		}
+	}
+	bw.WriteString("</svg>\n")
+	return bw.Flush()
+}
+
+func (b *AvailabilityResult) ASCII() string {
+	var buffer bytes.Buffer
+	RenderASCII(&buffer, b)
+	return buffer.String()
+}
@@ -0,0 +1,63 @@
+package availability
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderASCII(t *testing.T) {
+	t1 := time.Date(1982, 2, 7, 0, 0, 0, 0, time.UTC)
+	av := NewAvailability(Minute15)
+	av.Set(t1.Add(9*time.Hour), t1.Add(17*time.Hour+30*time.Minute), 1)
+	av.Set(t1.Add(33*time.Hour), t1.Add(35*time.Hour), 1)
+
+	//w
+	ascii := av.Get(t1.Add(6*time.Hour), t1.Add(36*time.Hour), Minute15).ASCII()
+
+	//t
+	expected := `1982-02-07T06:00:00Z - 1982-02-08T12:00:00Z, res: 15 min
+            000000000011111111112222
+            012345678901234567890123
+1982-02-07        ...########+......
+1982-02-08  .........##.            
+`
+	if ascii != expected {
+		t.Errorf("the grid should be\n%s\nwas\n%s", expected, ascii)
+	}
+}
+
+func TestRenderASCIIWithDayResolution(t *testing.T) {
+	t1 := time.Date(1982, 2, 7, 0, 0, 0, 0, time.UTC)
+	av := NewAvailability(Day)
+	av.Set(t1.Add(24*time.Hour), t1.Add(48*time.Hour), 1)
+
+	ascii := av.Get(t1, t1.Add(72*time.Hour), Day).ASCII()
+
+	if !strings.HasSuffix(ascii, "1982-02-07  .\n1982-02-08  #\n1982-02-09  .\n") {
+		t.Errorf("there should be one cell per day, was\n%s", ascii)
+	}
+}
+
+func TestRenderSVG(t *testing.T) {
+	t1 := time.Date(1982, 2, 7, 0, 0, 0, 0, time.UTC)
+	av := NewAvailability(Hour)
+	av.Set(t1, t1.Add(2*time.Hour), 1)
+
+	var buffer bytes.Buffer
+	if err := RenderSVG(&buffer, av.Get(t1, t1.Add(24*time.Hour), Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	svg := buffer.String()
+	if !strings.HasPrefix(svg, "<svg") || !strings.HasSuffix(svg, "</svg>\n") {
+		t.Errorf("the output should be an svg document, was %s", svg)
+	}
+	if c := strings.Count(svg, "<rect"); c != 24 {
+		t.Errorf("there should be 24 cells, were %d", c)
+	}
+	if c := strings.Count(svg, `fill-opacity="1.00"`); c != 2 {
+		t.Errorf("2 cells should be fully available, %d were", c)
+	}
+}
@@ -51,3 +51,11 @@ func ParseTimeResolution(s string) TimeResolution {
 	}
 	return undefined
 }
+
+func (tr TimeResolution) IsValid() bool {
+	switch tr {
+	case sec, Minute, Minute5, Minute15, Hour, Day:
+		return true
+	}
+	return false
+}
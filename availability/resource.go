@@ -0,0 +1,110 @@
+package availability
+
+import (
+	"time"
+)
+
+// Resource describes what an availability belongs to, e.g. a room with its
+// room type, the property as group and attributes like capacity or tags.
+type Resource struct {
+	Id         string            `json:"id"`
+	Type       string            `json:"type,omitempty"`
+	Group      string            `json:"group,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// ResourceFilter matches resources by type, group and attributes, empty
+// fields match everything.
+type ResourceFilter struct {
+	Type       string
+	Group      string
+	Attributes map[string]string
+}
+
+type GroupResult struct {
+	Resolution TimeResolution `json:"resolution"`
+	From       time.Time      `json:"from"`
+	To         time.Time      `json:"to"`
+	Members    []string       `json:"members"`
+	Counts     []int          `json:"counts"`
+}
+
+func (r *Resource) Matches(filter ResourceFilter) bool {
+	if filter.Type != "" && r.Type != filter.Type || filter.Group != "" && r.Group != filter.Group {
+		return false
+	}
+	for key, value := range filter.Attributes {
+		if r.Attributes[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// copy returns a copy of r that does not share its attributes.
+func (r *Resource) copy() *Resource {
+	if r == nil {
+		return nil
+	}
+	copied := *r
+	if r.Attributes != nil {
+		copied.Attributes = make(map[string]string, len(r.Attributes))
+		for key, value := range r.Attributes {
+			copied.Attributes[key] = value
+		}
+	}
+	return &copied
+}
+
+func (av *Availability) Resource() *Resource {
+	return av.resource
+}
+
+func (av *Availability) SetResource(resource *Resource) {
+	av.resource = resource
+}
+
+// CountAvailable counts for every unit of res between from and to how many
+// members of avc matching filter are available. The resource is stored with
+// the availability, so every availability of avc is loaded to match it.
+func CountAvailable(avc AvailabilityCollection, filter ResourceFilter, from, to time.Time, res TimeResolution) *GroupResult {
+	from, to = RoundDown(from, res), RoundUp(to, res)
+	result := &GroupResult{
+		Resolution: res,
+		From:       from,
+		To:         to,
+		Members:    []string{},
+		Counts:     make([]int, TimeToUnit(to, res)-TimeToUnit(from, res)),
+	}
+	ids, _ := avc.List("", "", 0)
+	for _, id := range ids {
+		av := avc.FindAvailabilityById(id)
+		if av == nil {
+			continue
+		}
+		resource := av.Resource()
+		if resource == nil {
+			resource = &Resource{Id: id}
+		}
+		if !resource.Matches(filter) {
+			continue
+		}
+		result.Members = append(result.Members, id)
+		data := av.Get(from, to, res).Data
+		for i := range result.Counts {
+			result.Counts[i] += int(data.Bit(i))
+		}
+	}
+	return result
+}
+
+// Any returns which units have at least one available member.
+func (g *GroupResult) Any() *Bitset {
+	any := NewBitset(len(g.Counts))
+	for i, count := range g.Counts {
+		if count > 0 {
+			any.SetBit(i, 1)
+		}
+	}
+	return any
+}
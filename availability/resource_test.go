@@ -0,0 +1,132 @@
+package availability
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func saveRoom(avc AvailabilityCollection, id, roomType, property string, from, to time.Time) {
+	av := NewAvailability(Hour)
+	av.SetResource(&Resource{Id: id, Type: roomType, Group: property, Attributes: map[string]string{"view": "sea"}})
+	av.Set(from, to, 1)
+	avc.SaveAvailability(id, av)
+}
+
+func TestResourceMatches(t *testing.T) {
+	resource := &Resource{Id: "room-12", Type: "deluxe", Group: "hotel-1", Attributes: map[string]string{"view": "sea", "beds": "2"}}
+
+	for _, tc := range []struct {
+		filter  ResourceFilter
+		matches bool
+	}{
+		{ResourceFilter{}, true},
+		{ResourceFilter{Type: "deluxe", Group: "hotel-1"}, true},
+		{ResourceFilter{Type: "standard"}, false},
+		{ResourceFilter{Group: "hotel-2"}, false},
+		{ResourceFilter{Attributes: map[string]string{"view": "sea"}}, true},
+		{ResourceFilter{Attributes: map[string]string{"view": "garden"}}, false},
+	} {
+		if m := resource.Matches(tc.filter); m != tc.matches {
+			t.Errorf("matching %+v should be %v, was %v", tc.filter, tc.matches, m)
+		}
+	}
+}
+
+func TestCountAvailableShouldCountFreeMembersPerBucket(t *testing.T) {
+	avc := NewAvailabilityCollection()
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	saveRoom(avc, "room-12", "deluxe", "hotel-1", t1, t1.Add(3*day))
+	saveRoom(avc, "room-13", "deluxe", "hotel-1", t1.Add(day), t1.Add(2*day+12*time.Hour))
+	saveRoom(avc, "room-14", "standard", "hotel-1", t1, t1.Add(3*day))
+	saveRoom(avc, "room-15", "deluxe", "hotel-2", t1, t1.Add(3*day))
+	avc.SaveAvailability("room-16", NewAvailability(Hour))
+
+	//w
+	result := CountAvailable(avc, ResourceFilter{Type: "deluxe", Group: "hotel-1"}, t1, t1.Add(3*day), Day)
+
+	//t
+	if len(result.Members) != 2 {
+		t.Errorf("2 members should match, were %v", result.Members)
+	}
+	if len(result.Counts) != 3 || result.Counts[0] != 1 || result.Counts[1] != 2 || result.Counts[2] != 1 {
+		t.Errorf("the counts should be [1 2 1], were %v", result.Counts)
+	}
+	if result.Any().Count() != 3 {
+		t.Errorf("a member should be available on every day, was %v", result.Any().Bytes())
+	}
+}
+
+func TestResourceShouldBeStoredWithAvailability(t *testing.T) {
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	sc := openTestSQLCollection(t)
+	saveRoom(sc, "room-12", "deluxe", "hotel-1", t1, t1.Add(time.Hour))
+
+	data, _ := json.Marshal(sc.FindAvailabilityById("room-12"))
+	loaded := new(Availability)
+	json.Unmarshal(data, loaded)
+
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	resource := loaded.Resource()
+	if resource == nil || resource.Type != "deluxe" || resource.Group != "hotel-1" || resource.Attributes["view"] != "sea" {
+		t.Errorf("the resource should survive SQL and JSON, was %+v", resource)
+	}
+}
+
+func TestResourceChangesShouldBePersisted(t *testing.T) {
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	fc := openTestFileCollection(t, dir, FileCollectionOptions{})
+	saveRoom(fc, "room-12", "deluxe", "hotel-1", t1, t1.Add(time.Hour))
+	sc := openTestSQLCollection(t)
+	saveRoom(sc, "room-12", "deluxe", "hotel-1", t1, t1.Add(time.Hour))
+	opened, _ := sc.Open("room-12", 4)
+
+	//w the same availabilities are saved again
+	for _, save := range []struct {
+		av  *Availability
+		avc AvailabilityCollection
+	}{{fc.FindAvailabilityById("room-12"), fc}, {opened, sc}} {
+		save.av.SetResource(&Resource{Id: "room-12", Type: "suite"})
+		save.avc.SaveAvailability("room-12", save.av)
+	}
+
+	//t
+	recovered := openTestFileCollection(t, dir, FileCollectionOptions{})
+	for name, av := range map[string]*Availability{"file": recovered.FindAvailabilityById("room-12"), "sql": sc.FindAvailabilityById("room-12")} {
+		if resource := av.Resource(); resource == nil || resource.Type != "suite" {
+			t.Errorf("the %s collection should store the new resource, was %+v", name, resource)
+		}
+	}
+}
+
+func TestSQLCollectionShouldAddMetadataColumn(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	db.Exec(`CREATE TABLE availability_resources (id TEXT PRIMARY KEY, resolution INTEGER NOT NULL, segment_length INTEGER NOT NULL,
+		default_value INTEGER NOT NULL, encoding INTEGER NOT NULL, version INTEGER NOT NULL)`)
+	db.Exec(`INSERT INTO availability_resources VALUES ('room-12', 3600, 24, 0, 0, 1)`)
+
+	//w
+	sc, err := NewSQLAvailabilityCollection(db)
+
+	//t
+	if err != nil {
+		t.Fatal(err)
+	}
+	if av := sc.FindAvailabilityById("room-12"); av == nil || av.Version() != 1 {
+		t.Errorf("the existing availability should be found, was %v, %v", av, sc.Err())
+	}
+	saveRoom(sc, "room-13", "deluxe", "hotel-1", time.Now(), time.Now())
+	if resource := sc.FindAvailabilityById("room-13").Resource(); resource == nil || resource.Type != "deluxe" {
+		t.Errorf("the resource should be stored, was %+v", resource)
+	}
+}
@@ -0,0 +1,123 @@
+package availability
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidStay       = errors.New("departure must be after arrival")
+	ErrNotAvailable      = errors.New("not available")
+	ErrClosedToArrival   = errors.New("closed to arrival")
+	ErrClosedToDeparture = errors.New("closed to departure")
+	ErrMinLOS            = errors.New("minimum length of stay not reached")
+	ErrMaxLOS            = errors.New("maximum length of stay exceeded")
+)
+
+const restrictionSegmentLength = 64
+
+type Restriction struct {
+	ClosedToArrival   bool `json:"closed_to_arrival"`
+	ClosedToDeparture bool `json:"closed_to_departure"`
+	MinLOS            int  `json:"min_los"`
+	MaxLOS            int  `json:"max_los"`
+}
+
+// Restrictions stores stay restrictions per day, MinLOS and MaxLOS are counted
+// in nights and apply to stays arriving on that day, zero means unrestricted.
+type Restrictions struct {
+	closedToArrival   *SegmentedVector
+	closedToDeparture *SegmentedVector
+	minLOS            map[int]int
+	maxLOS            map[int]int
+}
+
+func NewRestrictions() *Restrictions {
+	return &Restrictions{
+		closedToArrival:   NewSegmentedVector(restrictionSegmentLength),
+		closedToDeparture: NewSegmentedVector(restrictionSegmentLength),
+		minLOS:            make(map[int]int),
+		maxLOS:            make(map[int]int),
+	}
+}
+
+func (r *Restrictions) SetClosedToArrival(from, to time.Time, closed bool) {
+	r.closedToArrival.Set(dayUnits(from, to, closed))
+}
+
+func (r *Restrictions) SetClosedToDeparture(from, to time.Time, closed bool) {
+	r.closedToDeparture.Set(dayUnits(from, to, closed))
+}
+
+func (r *Restrictions) SetMinLOS(from, to time.Time, nights int) {
+	setDays(r.minLOS, from, to, nights)
+}
+
+func (r *Restrictions) SetMaxLOS(from, to time.Time, nights int) {
+	setDays(r.maxLOS, from, to, nights)
+}
+
+func (r *Restrictions) Set(from, to time.Time, restriction Restriction) {
+	r.SetClosedToArrival(from, to, restriction.ClosedToArrival)
+	r.SetClosedToDeparture(from, to, restriction.ClosedToDeparture)
+	r.SetMinLOS(from, to, restriction.MinLOS)
+	r.SetMaxLOS(from, to, restriction.MaxLOS)
+}
+
+func (r *Restrictions) Get(day time.Time) Restriction {
+	unit := TimeToUnit(day, Day)
+	return Restriction{
+		ClosedToArrival:   r.closedToArrival.Get(unit, unit+1)[0] == 1,
+		ClosedToDeparture: r.closedToDeparture.Get(unit, unit+1)[0] == 1,
+		MinLOS:            r.minLOS[unit],
+		MaxLOS:            r.maxLOS[unit],
+	}
+}
+
+// CheckStay returns nil if every night between arrival and departure is
+// available in av and no restriction prevents the stay, otherwise the error
+// of the first rule that failed.
+func (r *Restrictions) CheckStay(av *Availability, arrival, departure time.Time) error {
+	arrival, departure = RoundDown(arrival, Day), RoundDown(departure, Day)
+	nights := TimeToUnit(departure, Day) - TimeToUnit(arrival, Day)
+	if nights <= 0 {
+		return ErrInvalidStay
+	}
+	if !av.Get(arrival, departure, Day).All() {
+		return ErrNotAvailable
+	}
+	onArrival := r.Get(arrival)
+	if onArrival.ClosedToArrival {
+		return fmt.Errorf("%w on %s", ErrClosedToArrival, arrival.Format(csvDateLayout))
+	}
+	if r.Get(departure).ClosedToDeparture {
+		return fmt.Errorf("%w on %s", ErrClosedToDeparture, departure.Format(csvDateLayout))
+	}
+	if onArrival.MinLOS > 0 && nights < onArrival.MinLOS {
+		return fmt.Errorf("%w: %d nights required, %d requested", ErrMinLOS, onArrival.MinLOS, nights)
+	}
+	if onArrival.MaxLOS > 0 && nights > onArrival.MaxLOS {
+		return fmt.Errorf("%w: %d nights allowed, %d requested", ErrMaxLOS, onArrival.MaxLOS, nights)
+	}
+	return nil
+}
+
+func dayUnits(from, to time.Time, value bool) (int, int, byte) {
+	var b byte
+	if value {
+		b = 1
+	}
+	return TimeToUnit(from, Day), TimeToUnit(RoundUp(to, Day), Day), b
+}
+
+func setDays(days map[int]int, from, to time.Time, nights int) {
+	fromUnit, toUnit, _ := dayUnits(from, to, false)
+	for unit := fromUnit; unit < toUnit; unit++ {
+		if nights == 0 {
+			delete(days, unit)
+		} else {
+			days[unit] = nights
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package availability
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGetRestriction(t *testing.T) {
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	r := NewRestrictions()
+	r.Set(t1, t1.AddDate(0, 0, 7), Restriction{ClosedToArrival: true, MinLOS: 3})
+	r.SetClosedToArrival(t1.AddDate(0, 0, 2), t1.AddDate(0, 0, 3), false)
+
+	if exp, got := (Restriction{ClosedToArrival: true, MinLOS: 3}), r.Get(t1.Add(13*time.Hour)); got != exp {
+		t.Errorf("the restriction should be %v, was %v", exp, got)
+	}
+	if exp, got := (Restriction{MinLOS: 3}), r.Get(t1.AddDate(0, 0, 2)); got != exp {
+		t.Errorf("the restriction should be %v, was %v", exp, got)
+	}
+	if exp, got := (Restriction{}), r.Get(t1.AddDate(0, 0, 7)); got != exp {
+		t.Errorf("the restriction should be %v, was %v", exp, got)
+	}
+}
+
+func TestCheckStay(t *testing.T) {
+	// nights  |1  2  3  4  5  6  7  8  9  10|
+	// open    |#  #  #  #  #  .  #  #  #  # |
+	// CTA              x
+	// CTD                          x
+	// MinLOS           3 (nov 8-10)
+	nov := func(day int) time.Time { return time.Date(2026, 11, day, 0, 0, 0, 0, time.UTC) }
+	av := NewAvailability(Day)
+	av.Set(nov(1), nov(11), 1)
+	av.Set(nov(6), nov(7), 0)
+	r := NewRestrictions()
+	r.SetClosedToArrival(nov(4), nov(5), true)
+	r.SetClosedToDeparture(nov(8), nov(9), true)
+	r.SetMinLOS(nov(8), nov(11), 3)
+	r.SetMaxLOS(nov(1), nov(4), 2)
+
+	for _, tc := range []struct {
+		arrival, departure time.Time
+		err                error
+	}{
+		{nov(1), nov(3), nil},
+		{nov(1), nov(4), ErrMaxLOS},
+		{nov(3), nov(3), ErrInvalidStay},
+		{nov(5), nov(7), ErrNotAvailable},
+		{nov(4), nov(6), ErrClosedToArrival},
+		{nov(7), nov(8), ErrClosedToDeparture},
+		{nov(8), nov(10), ErrMinLOS},
+		{nov(8), nov(11), nil},
+		{nov(10), nov(12), ErrNotAvailable},
+	} {
+		if err := r.CheckStay(av, tc.arrival, tc.departure); !errors.Is(err, tc.err) || (tc.err == nil && err != nil) {
+			t.Errorf("stay from %v to %v should fail with %v, was %v", tc.arrival, tc.departure, tc.err, err)
+		}
+	}
+}
@@ -2,17 +2,34 @@ package availability
 
 import (
 	"bytes"
-	"math/big"
+	"math/bits"
 	"strconv"
 )
 
+type Segment interface {
+	Start() int
+	Bit(i int) uint
+	Read(dst *Bitset, at, from, n int)
+	SetRange(from, to int, value byte)
+	Clone() Segment
+	SizeInBytes() int
+	String() string
+}
+
+type SegmentEncoding int
+
+const (
+	BitEncoding SegmentEncoding = iota
+	ContainerEncoding
+)
+
 type BitSegment struct {
-	big.Int
 	start int
+	words []uint64
 }
 
 func (bs *BitSegment) SetUnit(position int, value byte) {
-	bs.SetBit(&bs.Int, position, uint(value))
+	bs.SetRange(position, position+1, value)
 }
 
 func NewBitSegment(start int) *BitSegment {
@@ -21,6 +38,69 @@ func NewBitSegment(start int) *BitSegment {
 	}
 }
 
+func (bs *BitSegment) Start() int {
+	return bs.start
+}
+
+func (bs *BitSegment) Bit(i int) uint {
+	if i/64 >= len(bs.words) {
+		return 0
+	}
+	return uint(bs.words[i/64]>>uint(i%64)) & 1
+}
+
+func (bs *BitSegment) Read(dst *Bitset, at, from, n int) {
+	copyBits(dst.words, at, bs.words, from, n)
+}
+
+func (bs *BitSegment) SetRange(from, to int, value byte) {
+	if from >= to {
+		return
+	}
+	if n := (to + 63) / 64; n > len(bs.words) {
+		if value == 0 {
+			to = len(bs.words) * 64
+		} else {
+			words := make([]uint64, n)
+			copy(words, bs.words)
+			bs.words = words
+		}
+	}
+	setBitRange(bs.words, from, to, value)
+}
+
+func (bs *BitSegment) Clone() Segment {
+	clone := NewBitSegment(bs.start)
+	clone.words = append([]uint64(nil), bs.words...)
+	return clone
+}
+
+func (bs *BitSegment) BitLen() int {
+	for i := len(bs.words) - 1; i >= 0; i-- {
+		if bs.words[i] != 0 {
+			return i*64 + bits.Len64(bs.words[i])
+		}
+	}
+	return 0
+}
+
+func (bs *BitSegment) SizeInBytes() int {
+	return (bs.BitLen() + 7) / 8
+}
+
+func (bs *BitSegment) equal(other *BitSegment) bool {
+	words, otherWords := bs.words, other.words
+	if len(words) < len(otherWords) {
+		words, otherWords = otherWords, words
+	}
+	for i, w := range words {
+		if i < len(otherWords) && w != otherWords[i] || i >= len(otherWords) && w != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 func (bs *BitSegment) String() string {
 	var buffer bytes.Buffer
 	for i := 0; i < bs.BitLen(); i++ {
@@ -30,66 +110,202 @@ func (bs *BitSegment) String() string {
 	return buffer.String()
 }
 
+type VectorOptions struct {
+	SegmentLength int
+	DefaultValue  byte
+	Encoding      SegmentEncoding
+}
+
 type SegmentedVector struct {
 	segmentLength int
-	segments      map[int]*BitSegment
+	defaultValue  byte
+	encoding      SegmentEncoding
+	segments      map[int]Segment
+	source        SegmentSource
+	cacheSize     int
+	used          map[int]int
+	tick          int
+	err           error
 }
 
 func NewSegmentedVector(segmentLength int) *SegmentedVector {
+	return NewSegmentedVectorWithOptions(VectorOptions{SegmentLength: segmentLength})
+}
+
+func NewSegmentedVectorWithEncoding(segmentLength int, encoding SegmentEncoding) *SegmentedVector {
+	return NewSegmentedVectorWithOptions(VectorOptions{SegmentLength: segmentLength, Encoding: encoding})
+}
+
+func NewSegmentedVectorWithOptions(options VectorOptions) *SegmentedVector {
 	return &SegmentedVector{
-		segmentLength: segmentLength,
-		segments:      make(map[int]*BitSegment),
+		segmentLength: options.SegmentLength,
+		defaultValue:  options.DefaultValue,
+		encoding:      options.Encoding,
+		segments:      make(map[int]Segment),
+	}
+}
+
+func (sv *SegmentedVector) Options() VectorOptions {
+	return VectorOptions{
+		SegmentLength: sv.segmentLength,
+		DefaultValue:  sv.defaultValue,
+		Encoding:      sv.encoding,
 	}
 }
 
 func (sv *SegmentedVector) Set(from, to int, value byte) {
-	segmentStart := sv.segmentStart(from)
-	segment := sv.getOrEmptyBitSegment(segmentStart)
-
-	for i, j := from, from%sv.segmentLength; i < to; i, j = i+1, j+1 {
-		if j == sv.segmentLength {
-			sv.storeSegment(segment)
-			segment = sv.getOrEmptyBitSegment(i)
-			j = 0
+	sv.load(from, to)
+	for segmentStart := sv.segmentStart(from); segmentStart < to; segmentStart += sv.segmentLength {
+		if sv.segments[segmentStart] == nil && value == sv.defaultValue {
+			continue
+		}
+		segment := sv.getOrEmptySegment(segmentStart)
+		segmentFrom, segmentTo := 0, sv.segmentLength
+		if from > segmentStart {
+			segmentFrom = from - segmentStart
 		}
-		segment.SetUnit(j, value)
+		if to < segmentStart+sv.segmentLength {
+			segmentTo = to - segmentStart
+		}
+		segment.SetRange(segmentFrom, segmentTo, value)
+		sv.storeSegment(segment)
 	}
-	sv.segments[segment.start] = segment
+	sv.store(from, to)
 }
 
-func (sv *SegmentedVector) storeSegment(segment *BitSegment) {
-	sv.segments[segment.start] = segment
+func (sv *SegmentedVector) storeSegment(segment Segment) {
+	sv.segments[segment.Start()] = segment
 }
 
 func (sv *SegmentedVector) segmentStart(i int) int {
+	if r := i % sv.segmentLength; r < 0 {
+		return i - r - sv.segmentLength
+	}
 	return i - i%sv.segmentLength
 }
 
 func (sv *SegmentedVector) Get(from, to int) []byte {
-	length := to - from
-	result := make([]byte, length)
-	currentBitSegment := sv.getOrEmptyBitSegment(sv.segmentStart(from))
-	for i, j := 0, from%sv.segmentLength; i < length; i, j = i+1, j+1 {
-		if j == sv.segmentLength {
-			currentBitSegment = sv.getOrEmptyBitSegment(i + from)
-			j = 0
+	return sv.GetBits(from, to).Bytes()
+}
+
+func (sv *SegmentedVector) GetBits(from, to int) *Bitset {
+	result := NewBitset(to - from)
+	sv.load(from, to)
+	for segmentStart := sv.segmentStart(from); segmentStart < to; segmentStart += sv.segmentLength {
+		segmentFrom, segmentTo := segmentStart, segmentStart+sv.segmentLength
+		if from > segmentFrom {
+			segmentFrom = from
+		}
+		if to < segmentTo {
+			segmentTo = to
+		}
+		if segment := sv.segments[segmentStart]; segment != nil {
+			segment.Read(result, segmentFrom-from, segmentFrom-segmentStart, segmentTo-segmentFrom)
+		} else if sv.defaultValue != 0 {
+			result.SetRange(segmentFrom-from, segmentTo-from, sv.defaultValue)
 		}
-		result[i] = byte(currentBitSegment.Bit(j))
 	}
+	sv.evict()
 	return result
 }
 
-func (sv *SegmentedVector) getOrEmptyBitSegment(startValue int) *BitSegment {
+func (sv *SegmentedVector) setData(from int, data []byte) {
+	for i := 0; i < len(data); {
+		j := i + 1
+		for j < len(data) && data[j] == data[i] {
+			j++
+		}
+		sv.Set(from+i, from+j, data[i])
+		i = j
+	}
+}
+
+// Clone returns a copy of all segments. For a vector with a source it reads
+// every segment of the source, the copy has no source.
+func (sv *SegmentedVector) Clone() *SegmentedVector {
+	clone := NewSegmentedVectorWithOptions(sv.Options())
+	for start, segment := range sv.allSegments() {
+		clone.segments[start] = segment.Clone()
+	}
+	return clone
+}
+
+func (sv *SegmentedVector) newSegment(start int) Segment {
+	var segment Segment
+	if sv.encoding == ContainerEncoding {
+		segment = NewContainerSegment(start, sv.segmentLength)
+	} else {
+		segment = NewBitSegment(start)
+	}
+	if sv.defaultValue != 0 {
+		segment.SetRange(0, sv.segmentLength, sv.defaultValue)
+	}
+	return segment
+}
+
+func (sv *SegmentedVector) getOrEmptySegment(startValue int) Segment {
 	if segment := sv.segments[startValue]; segment != nil {
 		return segment
 	}
-	return NewBitSegment(startValue)
+	return sv.newSegment(startValue)
+}
+
+func (sv *SegmentedVector) segmentEqual(segment, other Segment) bool {
+	if a, ok := segment.(*BitSegment); ok {
+		if b, ok := other.(*BitSegment); ok {
+			return a.equal(b)
+		}
+	}
+	if a, ok := segment.(*ContainerSegment); ok {
+		if b, ok := other.(*ContainerSegment); ok {
+			return a.equal(b)
+		}
+	}
+	for i := 0; i < sv.segmentLength; i++ {
+		if segment.Bit(i) != other.Bit(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// DropDefaultSegments and Trim go through all segments, including the ones
+// of the source, and return the size of the deleted segments.
+func (sv *SegmentedVector) DropDefaultSegments() int {
+	var dropped []int
+	size := 0
+	for start, segment := range sv.allSegments() {
+		if sv.segmentEqual(segment, sv.newSegment(start)) {
+			dropped = append(dropped, start)
+			size += segment.SizeInBytes()
+		}
+	}
+	sv.deleteSegments(dropped)
+	return size
+}
+
+func (sv *SegmentedVector) Trim(before int, archive func(Segment)) int {
+	var trimmed []int
+	size := 0
+	for start, segment := range sv.allSegments() {
+		if start+sv.segmentLength <= before {
+			if archive != nil {
+				archive(segment)
+			}
+			trimmed = append(trimmed, start)
+			size += segment.SizeInBytes()
+		}
+	}
+	sv.deleteSegments(trimmed)
+	return size
 }
 
+// SizeInBytes is the size of the segments held in memory, for a vector with
+// a source only the cached ones.
 func (sv *SegmentedVector) SizeInBytes() int {
 	var sizeInBytes int
 	for _, segment := range sv.segments {
-		sizeInBytes += len(segment.Bytes())
+		sizeInBytes += segment.SizeInBytes()
 	}
 	return sizeInBytes
 }
@@ -97,7 +313,7 @@ func (sv *SegmentedVector) SizeInBytes() int {
 func (sv *SegmentedVector) String() string {
 	var buffer bytes.Buffer
 	for _, segment := range sv.segments {
-		buffer.WriteString(strconv.Itoa(segment.start))
+		buffer.WriteString(strconv.Itoa(segment.Start()))
 		buffer.WriteString("->")
 		buffer.WriteString(segment.String())
 		buffer.WriteRune('\n')
@@ -1,6 +1,7 @@
 package availability
 
 import (
+	"bytes"
 	"testing"
 )
 
@@ -10,3 +11,172 @@ func TestNewVectorShouldNotBeNil(t *testing.T) {
 		t.Error("a new segmented vector hould not be nil")
 	}
 }
+
+func TestSetAndGetAcrossSegments(t *testing.T) {
+	vector := NewSegmentedVector(7)
+
+	vector.Set(5, 17, 1)
+
+	data := vector.Get(3, 19)
+	expected := []byte{0, 0, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 0}
+	if !bytes.Equal(expected, data) {
+		t.Errorf("data should be equal to %v, was %v", expected, data)
+	}
+}
+
+func benchmarkSetWorkingHours(b *testing.B, encoding SegmentEncoding, segmentLength int) {
+	var vector *SegmentedVector
+	for i := 0; i < b.N; i++ {
+		vector = NewSegmentedVectorWithEncoding(segmentLength, encoding)
+		for day := 0; day < 365; day++ {
+			start := day * segmentLength
+			vector.Set(start+segmentLength*9/24, start+segmentLength*17/24, 1)
+		}
+	}
+	b.ReportMetric(float64(vector.SizeInBytes()), "bytes")
+}
+
+func benchmarkGetWorkingHours(b *testing.B, encoding SegmentEncoding, segmentLength int) {
+	vector := NewSegmentedVectorWithEncoding(segmentLength, encoding)
+	for day := 0; day < 365; day++ {
+		start := day * segmentLength
+		vector.Set(start+segmentLength*9/24, start+segmentLength*17/24, 1)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		vector.Get(segmentLength*100, segmentLength*107)
+	}
+}
+
+func BenchmarkSetYearMinute5BitEncoding(b *testing.B) {
+	benchmarkSetWorkingHours(b, BitEncoding, int(Day/Minute5))
+}
+
+func BenchmarkSetYearMinute5ContainerEncoding(b *testing.B) {
+	benchmarkSetWorkingHours(b, ContainerEncoding, int(Day/Minute5))
+}
+
+func BenchmarkSetYearSecBitEncoding(b *testing.B) {
+	benchmarkSetWorkingHours(b, BitEncoding, int(Day/sec))
+}
+
+func BenchmarkSetYearSecContainerEncoding(b *testing.B) {
+	benchmarkSetWorkingHours(b, ContainerEncoding, int(Day/sec))
+}
+
+func BenchmarkGetWeekMinute5BitEncoding(b *testing.B) {
+	benchmarkGetWorkingHours(b, BitEncoding, int(Day/Minute5))
+}
+
+func BenchmarkGetWeekMinute5ContainerEncoding(b *testing.B) {
+	benchmarkGetWorkingHours(b, ContainerEncoding, int(Day/Minute5))
+}
+
+func TestGetUntouchedSegmentShouldReturnDefaultValue(t *testing.T) {
+	vector := NewSegmentedVectorWithOptions(VectorOptions{SegmentLength: 7, DefaultValue: 1})
+
+	vector.Set(9, 11, 0)
+
+	data := vector.Get(5, 16)
+	expected := []byte{1, 1, 1, 1, 0, 0, 1, 1, 1, 1, 1}
+	if !bytes.Equal(expected, data) {
+		t.Errorf("data should be equal to %v, was %v", expected, data)
+	}
+	if l := len(vector.segments); l != 1 {
+		t.Errorf("only the touched segment should be stored, %d were", l)
+	}
+}
+
+func TestSetDefaultValueShouldNotStoreNewSegments(t *testing.T) {
+	vector := NewSegmentedVector(7)
+
+	vector.Set(0, 70, 0)
+
+	if l := len(vector.segments); l != 0 {
+		t.Errorf("no segment should be stored, %d were", l)
+	}
+}
+
+func TestDropDefaultSegments(t *testing.T) {
+	vector := NewSegmentedVector(64)
+	vector.Set(0, 640, 1)
+	vector.Set(64, 640, 0)
+
+	freed := vector.DropDefaultSegments()
+
+	if l := len(vector.segments); l != 1 {
+		t.Errorf("only one segment should be left, %d were", l)
+	}
+	if freed != 0 {
+		t.Errorf("empty segments should not have used memory, freed %d bytes", freed)
+	}
+	if c := vector.Get(0, 640); !bytes.Equal(c[:64], bytes.Repeat([]byte{1}, 64)) {
+		t.Errorf("the remaining segment should be unchanged, was %v", c[:64])
+	}
+}
+
+func TestTrimShouldArchiveOldSegments(t *testing.T) {
+	vector := NewSegmentedVector(64)
+	vector.Set(0, 640, 1)
+	var archived []int
+
+	freed := vector.Trim(300, func(segment Segment) {
+		archived = append(archived, segment.Start())
+	})
+
+	if l := len(archived); l != 4 {
+		t.Errorf("4 segments should have been archived, %d were", l)
+	}
+	if freed != 4*8 {
+		t.Errorf("%d bytes should have been freed, were %d", 4*8, freed)
+	}
+	if data := vector.Get(256, 320); data[0] != 1 {
+		t.Errorf("the segment containing the cutoff should be kept")
+	}
+}
+
+func benchmarkSetRange(b *testing.B, res TimeResolution, units int) {
+	vector := NewSegmentedVector(int(Day / res))
+	start := int(Day/res) * 3
+	b.SetBytes(int64(units))
+	for i := 0; i < b.N; i++ {
+		vector.Set(start+7, start+7+units, byte(i%2))
+	}
+}
+
+func benchmarkGetRange(b *testing.B, res TimeResolution, units int) {
+	vector := NewSegmentedVector(int(Day / res))
+	start := int(Day/res) * 3
+	for i := start; i < start+units; i += 5 {
+		vector.Set(i, i+3, 1)
+	}
+	b.SetBytes(int64(units))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		vector.Get(start+7, start+7+units)
+	}
+}
+
+func BenchmarkSetDayHour(b *testing.B) {
+	benchmarkSetRange(b, Hour, 24)
+}
+
+func BenchmarkSetWeekMinute5(b *testing.B) {
+	benchmarkSetRange(b, Minute5, 7*288)
+}
+
+func BenchmarkSetMonthMinute(b *testing.B) {
+	benchmarkSetRange(b, Minute, 30*1440)
+}
+
+func BenchmarkGetDayHour(b *testing.B) {
+	benchmarkGetRange(b, Hour, 24)
+}
+
+func BenchmarkGetWeekMinute5(b *testing.B) {
+	benchmarkGetRange(b, Minute5, 7*288)
+}
+
+func BenchmarkGetMonthMinute(b *testing.B) {
+	benchmarkGetRange(b, Minute, 30*1440)
+}
@@ -0,0 +1,179 @@
+package availability
+
+import (
+	"math"
+	"sort"
+)
+
+// SegmentSource is the storage behind a lazily loaded SegmentedVector.
+// Segments are passed in the format of segmentBytes, a nil segment in
+// StoreSegments holds only the default value and can be deleted.
+type SegmentSource interface {
+	LoadSegments(from, to int) (map[int][]byte, error)
+	StoreSegments(segments map[int][]byte) error
+}
+
+// NewSegmentedVectorWithSource returns a vector that fetches its segments from
+// source when they are read and writes them back on every Set, keeping at
+// most cacheSize segments in memory.
+func NewSegmentedVectorWithSource(options VectorOptions, source SegmentSource, cacheSize int) *SegmentedVector {
+	sv := NewSegmentedVectorWithOptions(options)
+	sv.source = source
+	sv.cacheSize = cacheSize
+	sv.used = make(map[int]int)
+	return sv
+}
+
+func NewAvailabilityWithSource(res TimeResolution, options VectorOptions, source SegmentSource, cacheSize int) *Availability {
+	if options.SegmentLength == 0 {
+		options.SegmentLength = int(Day / res)
+	}
+	return LoadAvailability(res, NewSegmentedVectorWithSource(options, source, cacheSize))
+}
+
+// Err returns the first error of the segment source.
+func (sv *SegmentedVector) Err() error {
+	return sv.err
+}
+
+func (av *Availability) Err() error {
+	return av.data.Err()
+}
+
+// load makes sure the segments covering from and to are cached.
+func (sv *SegmentedVector) load(from, to int) {
+	if sv.source == nil {
+		return
+	}
+	first := sv.segmentStart(from)
+	for start := first; start < to; start += sv.segmentLength {
+		if _, ok := sv.used[start]; ok {
+			continue
+		}
+		loaded, err := sv.source.LoadSegments(first, to)
+		if err != nil {
+			sv.setErr(err)
+			return
+		}
+		for start := first; start < to; start += sv.segmentLength {
+			if _, ok := sv.used[start]; !ok && loaded[start] != nil {
+				sv.segments[start] = sv.segmentFromBytes(start, loaded[start])
+			}
+		}
+		break
+	}
+	for start := first; start < to; start += sv.segmentLength {
+		sv.tick++
+		sv.used[start] = sv.tick
+	}
+}
+
+// store writes the segments covering from and to back to the source and
+// evicts the least recently used segments from the cache.
+func (sv *SegmentedVector) store(from, to int) {
+	if sv.source == nil {
+		return
+	}
+	segments := make(map[int][]byte)
+	for start := sv.segmentStart(from); start < to; start += sv.segmentLength {
+		segment := sv.segments[start]
+		if segment != nil {
+			segments[start] = sv.encodeSegment(segment)
+		}
+	}
+	if len(segments) > 0 {
+		sv.setErr(sv.source.StoreSegments(segments))
+	}
+	sv.evict()
+}
+
+// encodeSegment returns the segmentBytes of segment or nil if it holds only
+// the default value.
+func (sv *SegmentedVector) encodeSegment(segment Segment) []byte {
+	if sv.segmentEqual(segment, sv.newSegment(segment.Start())) {
+		return nil
+	}
+	return sv.segmentBytes(segment)
+}
+
+func (sv *SegmentedVector) encodeSegments() (map[int][]byte, error) {
+	all, err := sv.loadAllSegments()
+	if err != nil {
+		return nil, err
+	}
+	segments := make(map[int][]byte, len(all))
+	for start, segment := range all {
+		segments[start] = sv.encodeSegment(segment)
+	}
+	return segments, nil
+}
+
+// allSegments returns the cached segments together with the ones of the
+// source, which are loaded without being cached.
+func (sv *SegmentedVector) allSegments() map[int]Segment {
+	segments, err := sv.loadAllSegments()
+	if err != nil {
+		sv.setErr(err)
+		return sv.segments
+	}
+	return segments
+}
+
+func (sv *SegmentedVector) loadAllSegments() (map[int]Segment, error) {
+	if sv.source == nil {
+		return sv.segments, nil
+	}
+	loaded, err := sv.source.LoadSegments(math.MinInt, math.MaxInt)
+	if err != nil {
+		return nil, err
+	}
+	segments := make(map[int]Segment, len(loaded)+len(sv.segments))
+	for start, b := range loaded {
+		if b != nil {
+			segments[start] = sv.segmentFromBytes(start, b)
+		}
+	}
+	for start, segment := range sv.segments {
+		segments[start] = segment
+	}
+	return segments, nil
+}
+
+// deleteSegments deletes segments from the cache and the source.
+func (sv *SegmentedVector) deleteSegments(starts []int) {
+	for _, start := range starts {
+		delete(sv.segments, start)
+		delete(sv.used, start)
+	}
+	if sv.source == nil || len(starts) == 0 {
+		return
+	}
+	segments := make(map[int][]byte, len(starts))
+	for _, start := range starts {
+		segments[start] = nil
+	}
+	sv.setErr(sv.source.StoreSegments(segments))
+}
+
+func (sv *SegmentedVector) evict() {
+	if sv.source == nil || len(sv.used) <= sv.cacheSize {
+		return
+	}
+	starts := make([]int, 0, len(sv.used))
+	for start := range sv.used {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool {
+		return sv.used[starts[i]] < sv.used[starts[j]]
+	})
+	for _, start := range starts[:len(starts)-sv.cacheSize] {
+		delete(sv.used, start)
+		delete(sv.segments, start)
+	}
+}
+
+func (sv *SegmentedVector) setErr(err error) {
+	if sv.err == nil {
+		sv.err = err
+	}
+}
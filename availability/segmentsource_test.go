@@ -0,0 +1,153 @@
+package availability
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type memSegmentSource struct {
+	segments map[int][]byte
+	loads    int
+	stores   int
+}
+
+func (ms *memSegmentSource) LoadSegments(from, to int) (map[int][]byte, error) {
+	ms.loads++
+	segments := make(map[int][]byte)
+	for start, b := range ms.segments {
+		if start >= from && start < to {
+			segments[start] = b
+		}
+	}
+	return segments, nil
+}
+
+func (ms *memSegmentSource) StoreSegments(segments map[int][]byte) error {
+	ms.stores++
+	for start, b := range segments {
+		if b == nil {
+			delete(ms.segments, start)
+		} else {
+			ms.segments[start] = b
+		}
+	}
+	return nil
+}
+
+func TestLazyAvailabilityShouldWriteThroughToSource(t *testing.T) {
+	source := &memSegmentSource{segments: make(map[int][]byte)}
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	av := NewAvailabilityWithSource(Hour, VectorOptions{}, source, 2)
+
+	//w
+	av.Set(t1, t1.Add(5*24*time.Hour), 1)
+	av.Set(t1.Add(24*time.Hour), t1.Add(48*time.Hour), 0)
+
+	//t
+	if n := len(source.segments); n != 4 {
+		t.Errorf("4 segments should be stored, %d were", n)
+	}
+	if n := len(av.data.segments); n > 2 {
+		t.Errorf("at most 2 segments should be cached, %d were", n)
+	}
+	reopened := NewAvailabilityWithSource(Hour, VectorOptions{}, source, 2)
+	if c := reopened.Get(t1, t1.Add(5*24*time.Hour), Day).Data.Bytes(); string(c) != "\x01\x00\x01\x01\x01" {
+		t.Errorf("the days should be 10111, were %v", c)
+	}
+	if err := reopened.Err(); err != nil {
+		t.Errorf("there should be no error, was %v", err)
+	}
+}
+
+func TestLazyAvailabilityShouldLoadHotSegmentsOnce(t *testing.T) {
+	source := &memSegmentSource{segments: make(map[int][]byte)}
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	NewAvailabilityWithSource(Hour, VectorOptions{}, source, 10).Set(t1, t1.Add(365*24*time.Hour), 1)
+	av := NewAvailabilityWithSource(Hour, VectorOptions{}, source, 10)
+	source.loads = 0
+
+	//w
+	for i := 0; i < 5; i++ {
+		av.Get(t1.Add(100*24*time.Hour), t1.Add(107*24*time.Hour), Hour)
+	}
+
+	//t
+	if source.loads != 1 {
+		t.Errorf("the week should be loaded once, was loaded %d times", source.loads)
+	}
+	if n := len(av.data.segments); n != 7 {
+		t.Errorf("only the 7 segments of the week should be cached, %d were", n)
+	}
+}
+
+func TestLazyAvailabilityShouldCopyAllSegments(t *testing.T) {
+	source := &memSegmentSource{segments: make(map[int][]byte)}
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	av := NewAvailabilityWithSource(Hour, VectorOptions{}, source, 2)
+	av.Set(t1, t1.Add(5*24*time.Hour), 1)
+
+	//w
+	clone := av.Clone()
+	data, _ := json.Marshal(av)
+	unmarshalled := new(Availability)
+	json.Unmarshal(data, unmarshalled)
+
+	//t
+	for name, copied := range map[string]*Availability{"clone": clone, "unmarshalled": unmarshalled} {
+		if c := copied.Get(t1, t1.Add(5*24*time.Hour), Day).Data.Count(); c != 5 {
+			t.Errorf("the %s should have 5 available days, had %d", name, c)
+		}
+	}
+	if n := len(av.data.segments); n > 2 {
+		t.Errorf("at most 2 segments should be cached, %d were", n)
+	}
+}
+
+func TestLazyAvailabilityCompactShouldDeleteFromSource(t *testing.T) {
+	source := &memSegmentSource{segments: make(map[int][]byte)}
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	av := NewAvailabilityWithSource(Hour, VectorOptions{}, source, 2)
+	av.Set(t1, t1.Add(5*24*time.Hour), 1)
+
+	//w
+	archived := 0
+	av.Compact(t1.Add(3*24*time.Hour), func(Segment) { archived++ })
+
+	//t
+	if archived != 3 || len(source.segments) != 2 {
+		t.Errorf("3 segments should be archived and 2 left in the source, were %d and %d", archived, len(source.segments))
+	}
+	if c := av.Get(t1, t1.Add(5*24*time.Hour), Day).Data.Count(); c != 2 {
+		t.Errorf("2 days should be available, %d were", c)
+	}
+}
+
+type countingSegmentSource struct {
+	*memSegmentSource
+	loads int
+}
+
+func (s *countingSegmentSource) LoadSegments(from, to int) (map[int][]byte, error) {
+	s.loads++
+	return s.memSegmentSource.LoadSegments(from, to)
+}
+
+func TestLazyAvailabilitySizeShouldNotLoadSegments(t *testing.T) {
+	source := &countingSegmentSource{memSegmentSource: &memSegmentSource{segments: make(map[int][]byte)}}
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	av := NewAvailabilityWithSource(Hour, VectorOptions{}, source, 2)
+	av.Set(t1, t1.Add(5*24*time.Hour), 1)
+	loads := source.loads
+
+	//w
+	size := av.SizeInBytes()
+
+	//t
+	if source.loads != loads {
+		t.Errorf("the source should not be read, was %d times", source.loads-loads)
+	}
+	if size != 6 {
+		t.Errorf("only the 2 cached segments of 3 bytes should be measured, was %d bytes", size)
+	}
+}
@@ -0,0 +1,460 @@
+package availability
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrUnknownAvailability = errors.New("unknown availability")
+
+const sqlSchema = `
+CREATE TABLE IF NOT EXISTS availability_resources (
+	id             TEXT PRIMARY KEY,
+	resolution     INTEGER NOT NULL,
+	segment_length INTEGER NOT NULL,
+	default_value  INTEGER NOT NULL,
+	encoding       INTEGER NOT NULL,
+	version        INTEGER NOT NULL,
+	metadata       TEXT
+);
+CREATE TABLE IF NOT EXISTS availability_segments (
+	id            TEXT NOT NULL,
+	segment_start INTEGER NOT NULL,
+	bits          BLOB NOT NULL,
+	PRIMARY KEY (id, segment_start)
+);`
+
+// SQLAvailabilityCollection stores every segment of an availability as a row
+// (id, segment start, bits), so Get and Set only touch the segments covering
+// the requested range.
+type SQLAvailabilityCollection struct {
+	mu  sync.Mutex
+	db  *sql.DB
+	err error
+}
+
+func NewSQLAvailabilityCollection(db *sql.DB) (*SQLAvailabilityCollection, error) {
+	if _, err := db.Exec(sqlSchema); err != nil {
+		return nil, err
+	}
+	// databases created before resources were stored lack the metadata column
+	if rows, err := db.Query(`SELECT metadata FROM availability_resources LIMIT 0`); err == nil {
+		rows.Close()
+	} else if _, err := db.Exec(`ALTER TABLE availability_resources ADD COLUMN metadata TEXT`); err != nil {
+		return nil, err
+	}
+	return &SQLAvailabilityCollection{db: db}, nil
+}
+
+func (sc *SQLAvailabilityCollection) FindAvailabilityById(id string) *Availability {
+	av, err := sc.loadResource(sc.db, id)
+	if err == nil {
+		err = sc.loadSegments(sc.db, av, `SELECT segment_start, bits FROM availability_segments WHERE id = ?`, id)
+	}
+	if err != nil {
+		if err != ErrUnknownAvailability {
+			sc.setErr(err)
+		}
+		return nil
+	}
+	return av
+}
+
+func (sc *SQLAvailabilityCollection) SaveAvailability(id string, av *Availability) {
+	if sc.opened(id, av) != nil {
+		// every Set has been written through already, SetResource has not
+		metadata, err := resourceMetadata(av)
+		if err == nil {
+			_, err = sc.db.Exec(`UPDATE availability_resources SET metadata = ? WHERE id = ?`, metadata, id)
+		}
+		sc.setErr(err)
+		return
+	}
+	// encoded before the transaction, as a lazily loaded av reads from the db
+	segments, err := av.data.encodeSegments()
+	if err != nil {
+		sc.setErr(err)
+		return
+	}
+	sc.setErr(sc.inTx(func(tx *sql.Tx) error {
+		args, err := resourceArgs(id, av, av.version)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO availability_resources (id, resolution, segment_length, default_value, encoding, version, metadata)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET resolution = excluded.resolution, segment_length = excluded.segment_length,
+				default_value = excluded.default_value, encoding = excluded.encoding, version = excluded.version, metadata = excluded.metadata`,
+			args...); err != nil {
+			return err
+		}
+		return replaceSQLSegments(tx, id, segments)
+	}))
+}
+
+// CompareAndSave checks and writes the version in a single statement, so it
+// is safe against concurrent writers. An opened availability has written its
+// changes through already, each of them checking the version.
+func (sc *SQLAvailabilityCollection) CompareAndSave(id string, av *Availability, version int) error {
+	if source := sc.opened(id, av); source != nil {
+		return source.compareAndSave(av, version)
+	}
+	newVersion := av.version
+	if newVersion <= version {
+		newVersion = version + 1
+	}
+	segments, err := av.data.encodeSegments()
+	if err != nil {
+		return err
+	}
+	err = sc.inTx(func(tx *sql.Tx) error {
+		args, err := resourceArgs(id, av, newVersion)
+		if err != nil {
+			return err
+		}
+		var result sql.Result
+		if version == 0 {
+			result, err = tx.Exec(`INSERT INTO availability_resources (id, resolution, segment_length, default_value, encoding, version, metadata)
+				VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO NOTHING`, args...)
+		} else {
+			result, err = tx.Exec(`UPDATE availability_resources SET resolution = ?, segment_length = ?, default_value = ?, encoding = ?, version = ?, metadata = ?
+				WHERE id = ? AND version = ?`, append(args[1:], id, version)...)
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkRowsAffected(result); err != nil {
+			return err
+		}
+		return replaceSQLSegments(tx, id, segments)
+	})
+	if err == nil {
+		av.version = newVersion
+	}
+	return err
+}
+
+// opened returns the source of av if it has been opened under id.
+func (sc *SQLAvailabilityCollection) opened(id string, av *Availability) *sqlSegmentSource {
+	if source, ok := av.data.source.(*sqlSegmentSource); ok && source.sc == sc && source.id == id {
+		return source
+	}
+	return nil
+}
+
+// checkRowsAffected fails with ErrVersionConflict if a conditional write did
+// not find the expected version.
+func checkRowsAffected(result sql.Result) error {
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func resourceArgs(id string, av *Availability, version int) ([]interface{}, error) {
+	options := av.Options()
+	metadata, err := resourceMetadata(av)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{id, int(av.internalRes), options.SegmentLength, int(options.DefaultValue), int(options.Encoding), version, metadata}, nil
+}
+
+func resourceMetadata(av *Availability) (sql.NullString, error) {
+	if av.resource == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(av.resource)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+func replaceSQLSegments(tx *sql.Tx, id string, segments map[int][]byte) error {
+	if _, err := tx.Exec(`DELETE FROM availability_segments WHERE id = ?`, id); err != nil {
+		return err
+	}
+	return storeSQLSegments(tx, id, segments)
+}
+
+func (sc *SQLAvailabilityCollection) DeleteAvailability(id string) bool {
+	var deleted int64
+	sc.setErr(sc.inTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`DELETE FROM availability_resources WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		if deleted, err = result.RowsAffected(); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`DELETE FROM availability_segments WHERE id = ?`, id)
+		return err
+	}))
+	return deleted > 0
+}
+
+func (sc *SQLAvailabilityCollection) List(prefix, cursor string, limit int) ([]string, string) {
+	query := `SELECT id FROM availability_resources WHERE substr(id, 1, length(?)) = ? AND id > ? ORDER BY id`
+	args := []interface{}{prefix, prefix, cursor}
+	if limit > 0 {
+		// one more to know if there is a next page
+		query += ` LIMIT ?`
+		args = append(args, limit+1)
+	}
+	rows, err := sc.db.Query(query, args...)
+	if err != nil {
+		sc.setErr(err)
+		return nil, ""
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			sc.setErr(err)
+			return nil, ""
+		}
+		ids = append(ids, id)
+	}
+	sc.setErr(rows.Err())
+	return page(ids, limit)
+}
+
+func (sc *SQLAvailabilityCollection) Count() int {
+	var count int
+	sc.setErr(sc.db.QueryRow(`SELECT COUNT(*) FROM availability_resources`).Scan(&count))
+	return count
+}
+
+// Get loads only the segments covering from and to.
+func (sc *SQLAvailabilityCollection) Get(id string, from, to time.Time, res TimeResolution) (*AvailabilityResult, error) {
+	av, err := sc.loadRange(sc.db, id, from, to, res)
+	if err != nil {
+		return nil, err
+	}
+	return av.Get(from, to, res), nil
+}
+
+// Set loads, changes and writes back only the segments covering from and to.
+func (sc *SQLAvailabilityCollection) Set(id string, from, to time.Time, value byte) error {
+	return sc.inTx(func(tx *sql.Tx) error {
+		av, err := sc.loadRange(tx, id, from, to, 0)
+		if err != nil {
+			return err
+		}
+		av.Set(from, to, value)
+		if _, err := tx.Exec(`UPDATE availability_resources SET version = version + 1 WHERE id = ?`, id); err != nil {
+			return err
+		}
+		segments, err := av.data.encodeSegments()
+		if err != nil {
+			return err
+		}
+		return storeSQLSegments(tx, id, segments)
+	})
+}
+
+// Err returns the first error that occurred in one of the methods of
+// AvailabilityCollection.
+func (sc *SQLAvailabilityCollection) Err() error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.err
+}
+
+func (sc *SQLAvailabilityCollection) setErr(err error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.err == nil {
+		sc.err = err
+	}
+}
+
+type sqlQueryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func (sc *SQLAvailabilityCollection) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := sc.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// loadRange loads the segments covering from and to, rounded to res if it is
+// coarser than the internal resolution.
+func (sc *SQLAvailabilityCollection) loadRange(q sqlQueryer, id string, from, to time.Time, res TimeResolution) (*Availability, error) {
+	av, err := sc.loadResource(q, id)
+	if err != nil {
+		return nil, err
+	}
+	if res > av.internalRes {
+		from, to = RoundDown(from, res), RoundUp(to, res)
+	}
+	fromUnit := TimeToUnit(from, av.internalRes)
+	toUnit := TimeToUnit(RoundUp(to, av.internalRes), av.internalRes)
+	err = sc.loadSegments(q, av, `SELECT segment_start, bits FROM availability_segments WHERE id = ? AND segment_start >= ? AND segment_start < ?`,
+		id, av.data.segmentStart(fromUnit), toUnit)
+	return av, err
+}
+
+func (sc *SQLAvailabilityCollection) loadResource(q sqlQueryer, id string) (*Availability, error) {
+	var res, segmentLength, defaultValue, encoding, version int
+	var metadata sql.NullString
+	err := q.QueryRow(`SELECT resolution, segment_length, default_value, encoding, version, metadata FROM availability_resources WHERE id = ?`, id).
+		Scan(&res, &segmentLength, &defaultValue, &encoding, &version, &metadata)
+	if err == sql.ErrNoRows {
+		return nil, ErrUnknownAvailability
+	}
+	if err != nil {
+		return nil, err
+	}
+	data := NewSegmentedVectorWithOptions(VectorOptions{
+		SegmentLength: segmentLength,
+		DefaultValue:  byte(defaultValue),
+		Encoding:      SegmentEncoding(encoding),
+	})
+	av := LoadAvailability(TimeResolution(res), data).withVersion(version)
+	if metadata.Valid {
+		av.resource = new(Resource)
+		if err := json.Unmarshal([]byte(metadata.String), av.resource); err != nil {
+			return nil, err
+		}
+	}
+	return av, nil
+}
+
+func (sc *SQLAvailabilityCollection) loadSegments(q sqlQueryer, av *Availability, query string, args ...interface{}) error {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var start int
+		var bits []byte
+		if err := rows.Scan(&start, &bits); err != nil {
+			return err
+		}
+		av.data.storeSegment(av.data.segmentFromBytes(start, bits))
+	}
+	return rows.Err()
+}
+
+// Open returns the availability stored under id, which loads its segments on
+// demand and keeps at most cacheSize of them in memory.
+func (sc *SQLAvailabilityCollection) Open(id string, cacheSize int) (*Availability, error) {
+	av, err := sc.loadResource(sc.db, id)
+	if err != nil {
+		return nil, err
+	}
+	source := &sqlSegmentSource{sc: sc, id: id, version: av.version, saved: av.version}
+	lazy := NewAvailabilityWithSource(av.internalRes, av.Options(), source, cacheSize).withVersion(av.version)
+	lazy.resource = av.resource
+	return lazy, nil
+}
+
+// sqlSegmentSource writes only while the stored version is still the one it
+// expects, so writes of other availabilities opened for the same id fail with
+// ErrVersionConflict instead of being overwritten.
+type sqlSegmentSource struct {
+	sc      *SQLAvailabilityCollection
+	id      string
+	version int
+	// saved is the version the availability was opened or last saved with
+	saved int
+}
+
+func (s *sqlSegmentSource) LoadSegments(from, to int) (map[int][]byte, error) {
+	rows, err := s.sc.db.Query(`SELECT segment_start, bits FROM availability_segments WHERE id = ? AND segment_start >= ? AND segment_start < ?`,
+		s.id, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	segments := make(map[int][]byte)
+	for rows.Next() {
+		var start int
+		var bits []byte
+		if err := rows.Scan(&start, &bits); err != nil {
+			return nil, err
+		}
+		segments[start] = bits
+	}
+	return segments, rows.Err()
+}
+
+func (s *sqlSegmentSource) StoreSegments(segments map[int][]byte) error {
+	err := s.sc.inTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`UPDATE availability_resources SET version = version + 1 WHERE id = ? AND version = ?`, s.id, s.version)
+		if err != nil {
+			return err
+		}
+		if err := checkRowsAffected(result); err != nil {
+			return err
+		}
+		return storeSQLSegments(tx, s.id, segments)
+	})
+	if err == nil {
+		s.version++
+	}
+	return err
+}
+
+// compareAndSave fails if version is not the one av was opened or last saved
+// with, or if a write since then failed.
+func (s *sqlSegmentSource) compareAndSave(av *Availability, version int) error {
+	if version != s.saved {
+		return ErrVersionConflict
+	}
+	if err := av.Err(); err != nil {
+		return err
+	}
+	metadata, err := resourceMetadata(av)
+	if err != nil {
+		return err
+	}
+	result, err := s.sc.db.Exec(`UPDATE availability_resources SET metadata = ? WHERE id = ? AND version = ?`, metadata, s.id, s.version)
+	if err != nil {
+		return err
+	}
+	if err := checkRowsAffected(result); err != nil {
+		return err
+	}
+	s.saved = s.version
+	av.version = s.version
+	return nil
+}
+
+// storeSQLSegments upserts segments, nil segments are deleted.
+func storeSQLSegments(tx *sql.Tx, id string, segments map[int][]byte) error {
+	upsert, err := tx.Prepare(`INSERT INTO availability_segments (id, segment_start, bits) VALUES (?, ?, ?)
+		ON CONFLICT (id, segment_start) DO UPDATE SET bits = excluded.bits`)
+	if err != nil {
+		return err
+	}
+	defer upsert.Close()
+	for start, bits := range segments {
+		if bits == nil {
+			_, err = tx.Exec(`DELETE FROM availability_segments WHERE id = ? AND segment_start = ?`, id, start)
+		} else {
+			_, err = upsert.Exec(id, start, bits)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
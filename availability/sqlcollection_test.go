@@ -0,0 +1,200 @@
+package availability
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/glebarez/go-sqlite"
+)
+
+func openTestSQLCollection(t *testing.T) *SQLAvailabilityCollection {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection to :memory: opens a new database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	sc, err := NewSQLAvailabilityCollection(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sc
+}
+
+func TestSQLCollectionSaveAndFind(t *testing.T) {
+	sc := openTestSQLCollection(t)
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	av := NewAvailabilityWithOptions(Hour, VectorOptions{DefaultValue: 1, Encoding: ContainerEncoding})
+	av.Set(t1, t1.Add(50*time.Hour), 0)
+	av.Set(t1.Add(3*time.Hour), t1.Add(5*time.Hour), 1)
+
+	sc.SaveAvailability("room-12", av)
+	found := sc.FindAvailabilityById("room-12")
+
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if found == nil {
+		t.Fatal("the saved availability should be found")
+	}
+	if found.Version() != av.Version() || found.Options() != av.Options() || found.Resolution() != av.Resolution() {
+		t.Errorf("the found availability should have the same version and options")
+	}
+	if changes, _ := Diff(av, found, t1.Add(-24*time.Hour), t1.Add(96*time.Hour)); len(changes) != 0 {
+		t.Errorf("the found availability should be equal, changes were %v", changes)
+	}
+	if sc.FindAvailabilityById("room-13") != nil {
+		t.Errorf("an unknown id should not be found")
+	}
+}
+
+func TestSQLCollectionGetShouldLoadCoveringSegments(t *testing.T) {
+	sc := openTestSQLCollection(t)
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	av := NewAvailability(Hour)
+	av.Set(t1, t1.Add(30*24*time.Hour), 1)
+	sc.SaveAvailability("room-12", av)
+
+	//w
+	loaded, err := sc.loadRange(sc.db, "room-12", t1.Add(36*time.Hour), t1.Add(60*time.Hour), Hour)
+	result, _ := sc.Get("room-12", t1.Add(36*time.Hour), t1.Add(60*time.Hour), Day)
+
+	//t
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(loaded.data.segments); n != 2 {
+		t.Errorf("2 segments should be loaded, %d were", n)
+	}
+	if result.Data.Len() != 2 || result.Data.Count() != 2 {
+		t.Errorf("the daily result should be 2 available days, was %v", result.Data.Bytes())
+	}
+	if _, err := sc.Get("room-13", t1, t1.Add(time.Hour), Hour); err != ErrUnknownAvailability {
+		t.Errorf("the error should be %v, was %v", ErrUnknownAvailability, err)
+	}
+}
+
+func TestSQLCollectionSetShouldUpsertTouchedSegments(t *testing.T) {
+	sc := openTestSQLCollection(t)
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	av := NewAvailability(Hour)
+	av.Set(t1, t1.Add(48*time.Hour), 1)
+	sc.SaveAvailability("room-12", av)
+
+	//w
+	err1 := sc.Set("room-12", t1.Add(24*time.Hour), t1.Add(48*time.Hour), 0)
+	err2 := sc.Set("room-12", t1.Add(72*time.Hour), t1.Add(74*time.Hour), 1)
+
+	//t
+	if err1 != nil || err2 != nil {
+		t.Fatal(err1, err2)
+	}
+	var starts []int
+	rows, err := sc.db.Query(`SELECT segment_start FROM availability_segments WHERE id = ? ORDER BY segment_start`, "room-12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var start int
+		rows.Scan(&start)
+		starts = append(starts, start)
+	}
+	first := TimeToUnit(t1, Hour)
+	if len(starts) != 2 || starts[0] != first || starts[1] != first+72 {
+		t.Errorf("the emptied segment should be deleted and the new one inserted, segments were %v", starts)
+	}
+	found := sc.FindAvailabilityById("room-12")
+	if c := found.Get(t1, t1.Add(96*time.Hour), Hour).Data.Count(); c != 26 {
+		t.Errorf("26 hours should be available, %d were", c)
+	}
+	if v := found.Version(); v != av.Version()+2 {
+		t.Errorf("the version should be %d, was %d", av.Version()+2, v)
+	}
+}
+
+func TestSQLCollectionOpenShouldLoadLazily(t *testing.T) {
+	sc := openTestSQLCollection(t)
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	av := NewAvailability(Hour)
+	av.Set(t1, t1.Add(30*24*time.Hour), 1)
+	sc.SaveAvailability("room-12", av)
+
+	//w
+	lazy, err := sc.Open("room-12", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lazy.Set(t1.Add(24*time.Hour), t1.Add(48*time.Hour), 0)
+	result := lazy.Get(t1, t1.Add(3*24*time.Hour), Day)
+
+	//t
+	if b := result.Data.Bytes(); string(b) != "\x01\x00\x01" {
+		t.Errorf("the days should be 101, were %v", b)
+	}
+	if n := len(lazy.data.segments); n > 4 {
+		t.Errorf("at most 4 segments should be cached, %d were", n)
+	}
+	found := sc.FindAvailabilityById("room-12")
+	if c := found.Get(t1, t1.Add(30*24*time.Hour), Day).Data.Count(); c != 29 {
+		t.Errorf("the change should be written through, 29 days should be available, %d were", c)
+	}
+	if v := found.Version(); v != av.Version()+1 {
+		t.Errorf("the version should be %d, was %d", av.Version()+1, v)
+	}
+	sc.SaveAvailability("room-13", lazy)
+	if c := sc.FindAvailabilityById("room-13").Get(t1, t1.Add(30*24*time.Hour), Day).Data.Count(); c != 29 {
+		t.Errorf("saving under another id should copy all segments, 29 days should be available, %d were", c)
+	}
+	if _, err := sc.Open("room-14", 4); err != ErrUnknownAvailability {
+		t.Errorf("the error should be %v, was %v", ErrUnknownAvailability, err)
+	}
+}
+
+func TestSQLCollectionOpenedShouldNotOverwriteEachOther(t *testing.T) {
+	sc := openTestSQLCollection(t)
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	sc.SaveAvailability("room-12", NewAvailability(Hour))
+
+	//w two admins open the same version
+	first, _ := sc.Open("room-12", 4)
+	second, _ := sc.Open("room-12", 4)
+	version := first.Version()
+	first.Set(t1, t1.Add(time.Hour), 1)
+	err1 := sc.CompareAndSave("room-12", first, version)
+	second.Set(t1.Add(time.Hour), t1.Add(2*time.Hour), 1)
+	err2 := sc.CompareAndSave("room-12", second, version)
+
+	//t
+	if err1 != nil {
+		t.Errorf("the first save should not fail, %v", err1)
+	}
+	if err2 != ErrVersionConflict {
+		t.Errorf("the second save should fail with %v, was %v", ErrVersionConflict, err2)
+	}
+	if err := sc.CompareAndSave("room-12", first, version); err != ErrVersionConflict {
+		t.Errorf("saving with the old version should fail with %v, was %v", ErrVersionConflict, err)
+	}
+	if b := sc.FindAvailabilityById("room-12").Get(t1, t1.Add(2*time.Hour), Hour).Data.Bytes(); string(b) != "\x01\x00" {
+		t.Errorf("only the first change should be stored, was %v", b)
+	}
+}
+
+func TestSQLCollectionListAndDelete(t *testing.T) {
+	sc := openTestSQLCollection(t)
+	testListAndDelete(t, sc)
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	var segments int
+	sc.db.QueryRow(`SELECT COUNT(*) FROM availability_segments WHERE id = ?`, "room-13").Scan(&segments)
+	if segments != 0 {
+		t.Errorf("the segments should be deleted, %d were left", segments)
+	}
+}
+
+func TestSQLCollectionCompareAndSave(t *testing.T) {
+	testCompareAndSave(t, openTestSQLCollection(t))
+}
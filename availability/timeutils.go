@@ -1,9 +1,12 @@
 package availability
 
 import (
+	"errors"
 	"time"
 )
 
+var now = time.Now
+
 func TimeToUnit(t time.Time, res TimeResolution) int {
 	return int(t.Unix() / int64(res))
 }
@@ -21,3 +24,17 @@ func RoundUp(t time.Time, res TimeResolution) time.Time {
 	}
 	return t
 }
+
+func UnitToTime(unit int, res TimeResolution) time.Time {
+	return time.Unix(int64(unit)*int64(res), 0).UTC()
+}
+
+func ParseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("missing time")
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
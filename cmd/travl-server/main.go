@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/advincze/travl/availability"
+	"github.com/advincze/travl/server"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	res := flag.String("res", "5m", "internal resolution of new availabilities")
+	flag.Parse()
+
+	resolution := availability.ParseTimeResolution(*res)
+	if !resolution.IsValid() {
+		log.Fatalf("invalid resolution %q", *res)
+	}
+
+	srv := server.NewServer(availability.NewAvailabilityCollection(), resolution)
+	log.Printf("listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, srv))
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/advincze/travl/availability"
+)
+
+const usage = `usage: travl <command> <id> [flags]
+
+commands:
+  get <id>           show the availability of id between --from and --to
+  set <id>           set the range between --from and --to to --value
+  clear <id>         clear the range between --from and --to
+  diff <id> <other>  show the ranges where id and other differ
+  export <id>        print the stored availability of id as JSON
+
+flags:
+  --store  path of the collection store (default $TRAVL_STORE or travl.json)
+  --from   start of the range, date or RFC 3339 time
+  --to     end of the range, date or RFC 3339 time
+  --res    resolution of the result or of a new availability (default 5m)
+  --value  value to set, 0 or 1 (default 1)
+  --json   print JSON instead of an ASCII timeline
+  --svg    print an SVG heat map instead of an ASCII timeline
+`
+
+type options struct {
+	store  string
+	from   time.Time
+	to     time.Time
+	res    availability.TimeResolution
+	value  int
+	json   bool
+	svg    bool
+	resSet bool
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "travl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	if len(args) < 2 {
+		return errors.New("missing command or id\n\n" + usage)
+	}
+	command, ids, opts, err := parseArgs(args)
+	if err != nil {
+		return err
+	}
+
+	st, err := openStore(opts.store)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "get":
+		av, err := find(st, ids[0])
+		if err != nil {
+			return err
+		}
+		if err := requireRange(opts); err != nil {
+			return err
+		}
+		res := av.Resolution()
+		if opts.resSet {
+			res = opts.res
+		}
+		return printResult(out, av.Get(opts.from, opts.to, res), opts)
+	case "set", "clear":
+		if err := requireRange(opts); err != nil {
+			return err
+		}
+		av, err := st.FindAvailabilityById(ids[0])
+		if err != nil {
+			return err
+		}
+		if av == nil {
+			if command == "clear" {
+				return fmt.Errorf("availability %q not found", ids[0])
+			}
+			av = availability.NewAvailability(opts.res)
+		}
+		value := byte(opts.value)
+		if command == "clear" {
+			value = 0
+		}
+		av.Set(opts.from, opts.to, value)
+		if err := st.SaveAvailability(ids[0], av); err != nil {
+			return err
+		}
+		return st.flush()
+	case "diff":
+		if len(ids) < 2 {
+			return errors.New("diff needs two ids")
+		}
+		if err := requireRange(opts); err != nil {
+			return err
+		}
+		a, err := find(st, ids[0])
+		if err != nil {
+			return err
+		}
+		b, err := find(st, ids[1])
+		if err != nil {
+			return err
+		}
+		changes, err := availability.Diff(a, b, opts.from, opts.to)
+		if err != nil {
+			return err
+		}
+		return printChanges(out, changes, opts.json)
+	case "export":
+		av, err := find(st, ids[0])
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(out).Encode(av)
+	}
+	return fmt.Errorf("unknown command %q\n\n%s", command, usage)
+}
+
+func parseArgs(args []string) (string, []string, *options, error) {
+	command := args[0]
+	var ids []string
+	rest := args[1:]
+	for len(rest) > 0 && len(rest[0]) > 0 && rest[0][0] != '-' {
+		ids = append(ids, rest[0])
+		rest = rest[1:]
+	}
+	if len(ids) == 0 {
+		return "", nil, nil, errors.New("missing id")
+	}
+
+	opts := &options{}
+	fs := flag.NewFlagSet("travl", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	defaultStore := os.Getenv("TRAVL_STORE")
+	if defaultStore == "" {
+		defaultStore = "travl.json"
+	}
+	fs.StringVar(&opts.store, "store", defaultStore, "")
+	from := fs.String("from", "", "")
+	to := fs.String("to", "", "")
+	res := fs.String("res", "", "")
+	fs.IntVar(&opts.value, "value", 1, "")
+	fs.BoolVar(&opts.json, "json", false, "")
+	fs.BoolVar(&opts.svg, "svg", false, "")
+	if err := fs.Parse(rest); err != nil {
+		return "", nil, nil, err
+	}
+
+	var err error
+	if *from != "" {
+		if opts.from, err = availability.ParseTime(*from); err != nil {
+			return "", nil, nil, fmt.Errorf("invalid --from: %v", err)
+		}
+	}
+	if *to != "" {
+		if opts.to, err = availability.ParseTime(*to); err != nil {
+			return "", nil, nil, fmt.Errorf("invalid --to: %v", err)
+		}
+	}
+	opts.res = availability.Minute5
+	if *res != "" {
+		if opts.res = availability.ParseTimeResolution(*res); !opts.res.IsValid() {
+			return "", nil, nil, fmt.Errorf("invalid --res %q", *res)
+		}
+		opts.resSet = true
+	}
+	if opts.value != 0 && opts.value != 1 {
+		return "", nil, nil, fmt.Errorf("invalid --value %d", opts.value)
+	}
+	return command, ids, opts, nil
+}
+
+func requireRange(opts *options) error {
+	if opts.from.IsZero() || opts.to.IsZero() {
+		return errors.New("--from and --to are required")
+	}
+	if opts.to.Before(opts.from) {
+		return errors.New("--to must not be before --from")
+	}
+	return nil
+}
+
+func find(st *store, id string) (*availability.Availability, error) {
+	av, err := st.FindAvailabilityById(id)
+	if err != nil {
+		return nil, err
+	}
+	if av == nil {
+		return nil, fmt.Errorf("availability %q not found", id)
+	}
+	return av, nil
+}
+
+func printResult(out io.Writer, result *availability.AvailabilityResult, opts *options) error {
+	if opts.json {
+		return json.NewEncoder(out).Encode(result)
+	}
+	if opts.svg {
+		return availability.RenderSVG(out, result)
+	}
+	return availability.RenderASCII(out, result)
+}
+
+func printChanges(out io.Writer, changes []availability.Change, asJSON bool) error {
+	if asJSON {
+		return json.NewEncoder(out).Encode(changes)
+	}
+	for _, change := range changes {
+		fmt.Fprintf(out, "%s  %s  %d -> %d\n", change.From.Format(time.RFC3339), change.To.Format(time.RFC3339), change.Old, change.New)
+	}
+	return nil
+}
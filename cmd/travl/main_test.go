@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/advincze/travl/availability"
+)
+
+func travl(t *testing.T, store string, args ...string) string {
+	var out bytes.Buffer
+	if err := run(append(args, "--store", store), &out); err != nil {
+		t.Fatalf("travl %v should not fail, %v", args, err)
+	}
+	return out.String()
+}
+
+func TestSetThenGet(t *testing.T) {
+	store := filepath.Join(t.TempDir(), "travl.json")
+
+	travl(t, store, "set", "room-12", "--from", "2026-11-02", "--to", "2026-11-05", "--res", "day")
+	travl(t, store, "clear", "room-12", "--from", "2026-11-03", "--to", "2026-11-04")
+	out := travl(t, store, "get", "room-12", "--from", "2026-11-01", "--to", "2026-11-08", "--res", "day")
+
+	if !strings.Contains(out, "2026-11-02  #\n2026-11-03  .\n2026-11-04  #\n2026-11-05  .\n") {
+		t.Errorf("only nov 2 and 4 should be available, was\n%s", out)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	store := filepath.Join(t.TempDir(), "travl.json")
+	travl(t, store, "set", "room-12", "--from", "2026-11-02", "--to", "2026-11-05", "--res", "day")
+	travl(t, store, "set", "room-13", "--from", "2026-11-02", "--to", "2026-11-04", "--res", "day")
+
+	out := travl(t, store, "diff", "room-12", "room-13", "--from", "2026-11-01", "--to", "2026-11-08")
+
+	if exp := "2026-11-04T00:00:00Z  2026-11-05T00:00:00Z  1 -> 0\n"; out != exp {
+		t.Errorf("diff should be %q, was %q", exp, out)
+	}
+}
+
+func TestGetUnknownIdShouldFail(t *testing.T) {
+	store := filepath.Join(t.TempDir(), "travl.json")
+
+	err := run([]string{"get", "room-12", "--from", "2026-11-01", "--to", "2026-11-08", "--store", store}, &bytes.Buffer{})
+
+	if err == nil {
+		t.Errorf("get should fail for an unknown id")
+	}
+}
+
+func TestSetShouldNotOverwriteCorruptRecords(t *testing.T) {
+	store := filepath.Join(t.TempDir(), "travl.json")
+	corrupt := []byte(`{"room-12": {"resolution": 0}}`)
+	ioutil.WriteFile(store, corrupt, 0644)
+
+	err := run([]string{"set", "room-12", "--from", "2026-11-01", "--to", "2026-11-08", "--store", store}, &bytes.Buffer{})
+
+	if err == nil {
+		t.Errorf("set should fail for a corrupt record")
+	}
+	if data, _ := ioutil.ReadFile(store); !bytes.Equal(data, corrupt) {
+		t.Errorf("the corrupt record should be kept, the store was %s", data)
+	}
+}
+
+type failingSource struct{}
+
+func (failingSource) LoadSegments(from, to int) (map[int][]byte, error) {
+	return nil, errors.New("disk failure")
+}
+
+func (failingSource) StoreSegments(segments map[int][]byte) error {
+	return errors.New("disk failure")
+}
+
+func TestSaveShouldReturnMarshalErrors(t *testing.T) {
+	st, _ := openStore(filepath.Join(t.TempDir(), "travl.json"))
+	av := availability.NewAvailabilityWithSource(availability.Hour, availability.VectorOptions{}, failingSource{}, 2)
+
+	err := st.SaveAvailability("room-12", av)
+
+	if err == nil || !strings.Contains(err.Error(), "disk failure") {
+		t.Errorf("the error should be reported, was %v", err)
+	}
+	if _, ok := st.records["room-12"]; ok {
+		t.Errorf("no record should be stored")
+	}
+}
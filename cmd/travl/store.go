@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/advincze/travl/availability"
+)
+
+type store struct {
+	path    string
+	records map[string]json.RawMessage
+}
+
+func openStore(path string) (*store, error) {
+	s := &store{
+		path:    path,
+		records: make(map[string]json.RawMessage),
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.records); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// FindAvailabilityById returns nil for an unknown id and fails for a record
+// that can not be read, which must not be overwritten.
+func (s *store) FindAvailabilityById(id string) (*availability.Availability, error) {
+	record, ok := s.records[id]
+	if !ok {
+		return nil, nil
+	}
+	av := new(availability.Availability)
+	if err := json.Unmarshal(record, av); err != nil {
+		return nil, fmt.Errorf("availability %q is corrupt: %v", id, err)
+	}
+	return av, nil
+}
+
+func (s *store) SaveAvailability(id string, av *availability.Availability) error {
+	record, err := json.Marshal(av)
+	if err != nil {
+		return fmt.Errorf("availability %q can not be saved: %v", id, err)
+	}
+	s.records[id] = record
+	return nil
+}
+
+func (s *store) flush() error {
+	data, err := json.MarshalIndent(s.records, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
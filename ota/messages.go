@@ -0,0 +1,100 @@
+package ota
+
+import (
+	"encoding/xml"
+)
+
+const Namespace = "http://www.opentravel.org/OTA/2003/05"
+
+type HotelAvailNotifRQ struct {
+	XMLName             xml.Name            `xml:"OTA_HotelAvailNotifRQ"`
+	Xmlns               string              `xml:"xmlns,attr,omitempty"`
+	EchoToken           string              `xml:"EchoToken,attr,omitempty"`
+	TimeStamp           string              `xml:"TimeStamp,attr,omitempty"`
+	Version             string              `xml:"Version,attr,omitempty"`
+	AvailStatusMessages AvailStatusMessages `xml:"AvailStatusMessages"`
+}
+
+type AvailStatusMessages struct {
+	HotelCode string               `xml:"HotelCode,attr"`
+	Messages  []AvailStatusMessage `xml:"AvailStatusMessage"`
+}
+
+type AvailStatusMessage struct {
+	BookingLimit             *int                     `xml:"BookingLimit,attr"`
+	StatusApplicationControl StatusApplicationControl `xml:"StatusApplicationControl"`
+	RestrictionStatus        *RestrictionStatus       `xml:"RestrictionStatus"`
+}
+
+type StatusApplicationControl struct {
+	Start        string `xml:"Start,attr"`
+	End          string `xml:"End,attr"`
+	InvTypeCode  string `xml:"InvTypeCode,attr,omitempty"`
+	RatePlanCode string `xml:"RatePlanCode,attr,omitempty"`
+	Mon          string `xml:"Mon,attr,omitempty"`
+	Tue          string `xml:"Tue,attr,omitempty"`
+	Weds         string `xml:"Weds,attr,omitempty"`
+	Thur         string `xml:"Thur,attr,omitempty"`
+	Fri          string `xml:"Fri,attr,omitempty"`
+	Sat          string `xml:"Sat,attr,omitempty"`
+	Sun          string `xml:"Sun,attr,omitempty"`
+}
+
+type RestrictionStatus struct {
+	Status      string `xml:"Status,attr"`
+	Restriction string `xml:"Restriction,attr,omitempty"`
+}
+
+type HotelInvCountNotifRQ struct {
+	XMLName     xml.Name    `xml:"OTA_HotelInvCountNotifRQ"`
+	Xmlns       string      `xml:"xmlns,attr,omitempty"`
+	EchoToken   string      `xml:"EchoToken,attr,omitempty"`
+	TimeStamp   string      `xml:"TimeStamp,attr,omitempty"`
+	Version     string      `xml:"Version,attr,omitempty"`
+	Inventories Inventories `xml:"Inventories"`
+}
+
+type Inventories struct {
+	HotelCode   string      `xml:"HotelCode,attr"`
+	Inventories []Inventory `xml:"Inventory"`
+}
+
+type Inventory struct {
+	StatusApplicationControl StatusApplicationControl `xml:"StatusApplicationControl"`
+	InvCounts                []InvCount               `xml:"InvCounts>InvCount"`
+}
+
+type InvCount struct {
+	CountType string `xml:"CountType,attr,omitempty"`
+	Count     int    `xml:"Count,attr"`
+}
+
+type HotelAvailRS struct {
+	XMLName   xml.Name   `xml:"OTA_HotelAvailRS"`
+	Xmlns     string     `xml:"xmlns,attr,omitempty"`
+	EchoToken string     `xml:"EchoToken,attr,omitempty"`
+	TimeStamp string     `xml:"TimeStamp,attr,omitempty"`
+	Version   string     `xml:"Version,attr,omitempty"`
+	Success   *struct{}  `xml:"Success"`
+	RoomStays []RoomStay `xml:"RoomStays>RoomStay"`
+}
+
+type RoomStay struct {
+	RoomTypes         []RoomType        `xml:"RoomTypes>RoomType"`
+	TimeSpan          TimeSpan          `xml:"TimeSpan"`
+	BasicPropertyInfo BasicPropertyInfo `xml:"BasicPropertyInfo"`
+}
+
+type RoomType struct {
+	RoomTypeCode  string `xml:"RoomTypeCode,attr"`
+	NumberOfUnits int    `xml:"NumberOfUnits,attr,omitempty"`
+}
+
+type TimeSpan struct {
+	Start string `xml:"Start,attr"`
+	End   string `xml:"End,attr"`
+}
+
+type BasicPropertyInfo struct {
+	HotelCode string `xml:"HotelCode,attr"`
+}
@@ -0,0 +1,297 @@
+package ota
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/advincze/travl/availability"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	// countTypeAvailable is the OTA count type of available rooms
+	countTypeAvailable = "2"
+)
+
+var ErrUnknownMessage = errors.New("ota: unknown message type")
+
+// ResourceId joins the codes with a colon, ids must not contain a slash to be
+// served by the REST API.
+func ResourceId(hotelCode, roomCode string) string {
+	return hotelCode + ":" + roomCode
+}
+
+// Parse reads an OTA_HotelAvailNotifRQ or OTA_HotelInvCountNotifRQ and returns
+// the range updates keyed by ResourceId.
+func Parse(r io.Reader) ([]availability.RangeRecord, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	switch root.XMLName.Local {
+	case "OTA_HotelAvailNotifRQ":
+		var msg HotelAvailNotifRQ
+		if err := xml.Unmarshal(data, &msg); err != nil {
+			return nil, err
+		}
+		return msg.Records()
+	case "OTA_HotelInvCountNotifRQ":
+		var msg HotelInvCountNotifRQ
+		if err := xml.Unmarshal(data, &msg); err != nil {
+			return nil, err
+		}
+		return msg.Records()
+	}
+	return nil, ErrUnknownMessage
+}
+
+func Apply(avc availability.AvailabilityCollection, res availability.TimeResolution, r io.Reader) error {
+	records, err := Parse(r)
+	if err != nil {
+		return err
+	}
+	availability.ApplyRanges(avc, res, records)
+	return nil
+}
+
+func (msg *HotelAvailNotifRQ) Records() ([]availability.RangeRecord, error) {
+	var records []availability.RangeRecord
+	hotelCode := msg.AvailStatusMessages.HotelCode
+	for i, status := range msg.AvailStatusMessages.Messages {
+		var value byte
+		switch {
+		case status.StatusApplicationControl.RatePlanCode != "":
+			// restricts a rate plan, not the availability of the room type
+			continue
+		case status.RestrictionStatus != nil && (status.RestrictionStatus.Restriction == "" || status.RestrictionStatus.Restriction == "Master"):
+			switch status.RestrictionStatus.Status {
+			case "Open":
+				value = 1
+			case "Close":
+				value = 0
+			default:
+				return nil, fmt.Errorf("ota: message %d: invalid status %q", i+1, status.RestrictionStatus.Status)
+			}
+		case status.BookingLimit != nil:
+			if *status.BookingLimit > 0 {
+				value = 1
+			}
+		default:
+			continue
+		}
+		controlRecords, err := status.StatusApplicationControl.records(hotelCode, value)
+		if err != nil {
+			return nil, fmt.Errorf("ota: message %d: %v", i+1, err)
+		}
+		records = append(records, controlRecords...)
+	}
+	return records, nil
+}
+
+func (msg *HotelInvCountNotifRQ) Records() ([]availability.RangeRecord, error) {
+	var records []availability.RangeRecord
+	hotelCode := msg.Inventories.HotelCode
+	for i, inventory := range msg.Inventories.Inventories {
+		count, ok := 0, false
+		for _, invCount := range inventory.InvCounts {
+			// definite sold, out of order and the other counts do not make
+			// rooms available
+			if invCount.CountType == countTypeAvailable {
+				count += invCount.Count
+				ok = true
+			}
+		}
+		if !ok {
+			continue
+		}
+		var value byte
+		if count > 0 {
+			value = 1
+		}
+		controlRecords, err := inventory.StatusApplicationControl.records(hotelCode, value)
+		if err != nil {
+			return nil, fmt.Errorf("ota: inventory %d: %v", i+1, err)
+		}
+		records = append(records, controlRecords...)
+	}
+	return records, nil
+}
+
+func (c *StatusApplicationControl) records(hotelCode string, value byte) ([]availability.RangeRecord, error) {
+	if c.InvTypeCode == "" {
+		return nil, errors.New("missing InvTypeCode")
+	}
+	start, err := time.Parse(dateLayout, c.Start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Start: %v", err)
+	}
+	end, err := time.Parse(dateLayout, c.End)
+	if err != nil {
+		return nil, fmt.Errorf("invalid End: %v", err)
+	}
+	if end.Before(start) {
+		return nil, errors.New("End is before Start")
+	}
+
+	id := ResourceId(hotelCode, c.InvTypeCode)
+	// End is inclusive in OTA messages
+	end = end.AddDate(0, 0, 1)
+	weekdays := c.weekdays()
+	if weekdays == nil {
+		return []availability.RangeRecord{{Id: id, From: start, To: end, Value: value}}, nil
+	}
+
+	var records []availability.RangeRecord
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		if !weekdays[day.Weekday()] {
+			continue
+		}
+		if n := len(records); n > 0 && records[n-1].To.Equal(day) {
+			records[n-1].To = day.AddDate(0, 0, 1)
+			continue
+		}
+		records = append(records, availability.RangeRecord{Id: id, From: day, To: day.AddDate(0, 0, 1), Value: value})
+	}
+	return records, nil
+}
+
+func (c *StatusApplicationControl) weekdays() map[time.Weekday]bool {
+	flags := map[time.Weekday]string{
+		time.Monday:    c.Mon,
+		time.Tuesday:   c.Tue,
+		time.Wednesday: c.Weds,
+		time.Thursday:  c.Thur,
+		time.Friday:    c.Fri,
+		time.Saturday:  c.Sat,
+		time.Sunday:    c.Sun,
+	}
+	var weekdays map[time.Weekday]bool
+	for weekday, flag := range flags {
+		if flag == "" {
+			continue
+		}
+		if weekdays == nil {
+			weekdays = make(map[time.Weekday]bool)
+		}
+		weekdays[weekday] = flag == "true" || flag == "1"
+	}
+	return weekdays
+}
+
+// NewHotelAvailNotif builds an open/close message from day resolution results
+// keyed by room code.
+func NewHotelAvailNotif(hotelCode string, results map[string]*availability.AvailabilityResult) (*HotelAvailNotifRQ, error) {
+	msg := &HotelAvailNotifRQ{
+		Xmlns:               Namespace,
+		Version:             "1.0",
+		AvailStatusMessages: AvailStatusMessages{HotelCode: hotelCode},
+	}
+	err := eachRun(results, func(roomCode string, start, end time.Time, value byte) {
+		status := "Close"
+		if value == 1 {
+			status = "Open"
+		}
+		msg.AvailStatusMessages.Messages = append(msg.AvailStatusMessages.Messages, AvailStatusMessage{
+			StatusApplicationControl: newStatusApplicationControl(roomCode, start, end),
+			RestrictionStatus:        &RestrictionStatus{Status: status, Restriction: "Master"},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func NewHotelInvCountNotif(hotelCode string, results map[string]*availability.AvailabilityResult) (*HotelInvCountNotifRQ, error) {
+	msg := &HotelInvCountNotifRQ{
+		Xmlns:       Namespace,
+		Version:     "1.0",
+		Inventories: Inventories{HotelCode: hotelCode},
+	}
+	err := eachRun(results, func(roomCode string, start, end time.Time, value byte) {
+		msg.Inventories.Inventories = append(msg.Inventories.Inventories, Inventory{
+			StatusApplicationControl: newStatusApplicationControl(roomCode, start, end),
+			InvCounts:                []InvCount{{CountType: countTypeAvailable, Count: int(value)}},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// NewHotelAvailRS lists the room codes whose result is available for the
+// whole requested stay.
+func NewHotelAvailRS(hotelCode string, results map[string]*availability.AvailabilityResult) *HotelAvailRS {
+	rs := &HotelAvailRS{
+		Xmlns:   Namespace,
+		Version: "1.0",
+		Success: &struct{}{},
+	}
+	for _, roomCode := range sortedKeys(results) {
+		result := results[roomCode]
+		if result.Data.Len() == 0 || !result.All() {
+			continue
+		}
+		rs.RoomStays = append(rs.RoomStays, RoomStay{
+			RoomTypes:         []RoomType{{RoomTypeCode: roomCode, NumberOfUnits: 1}},
+			TimeSpan:          TimeSpan{Start: result.From.Format(dateLayout), End: result.To.Format(dateLayout)},
+			BasicPropertyInfo: BasicPropertyInfo{HotelCode: hotelCode},
+		})
+	}
+	return rs
+}
+
+func Marshal(msg interface{}) ([]byte, error) {
+	data, err := xml.MarshalIndent(msg, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+func newStatusApplicationControl(roomCode string, start, end time.Time) StatusApplicationControl {
+	return StatusApplicationControl{
+		Start:       start.Format(dateLayout),
+		End:         end.AddDate(0, 0, -1).Format(dateLayout),
+		InvTypeCode: roomCode,
+	}
+}
+
+func eachRun(results map[string]*availability.AvailabilityResult, fn func(roomCode string, start, end time.Time, value byte)) error {
+	for _, roomCode := range sortedKeys(results) {
+		result := results[roomCode]
+		if result.Resolution != availability.Day {
+			return fmt.Errorf("ota: result of %q does not have day resolution", roomCode)
+		}
+		for i := 0; i < result.Data.Len(); {
+			j := i + 1
+			for j < result.Data.Len() && result.Data.Bit(j) == result.Data.Bit(i) {
+				j++
+			}
+			fn(roomCode, result.From.AddDate(0, 0, i), result.From.AddDate(0, 0, j), result.Data.Bit(i))
+			i = j
+		}
+	}
+	return nil
+}
+
+func sortedKeys(results map[string]*availability.AvailabilityResult) []string {
+	keys := make([]string, 0, len(results))
+	for key := range results {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,207 @@
+package ota
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/advincze/travl/availability"
+	"github.com/advincze/travl/server"
+)
+
+const availNotif = `<?xml version="1.0" encoding="UTF-8"?>
+<OTA_HotelAvailNotifRQ xmlns="http://www.opentravel.org/OTA/2003/05" EchoToken="abc" Version="1.0">
+  <AvailStatusMessages HotelCode="BER1">
+    <AvailStatusMessage>
+      <StatusApplicationControl Start="2026-11-01" End="2026-11-07" InvTypeCode="DBL"/>
+      <RestrictionStatus Status="Open" Restriction="Master"/>
+    </AvailStatusMessage>
+    <AvailStatusMessage>
+      <StatusApplicationControl Start="2026-11-01" End="2026-11-07" InvTypeCode="DBL" Sat="true" Sun="true"/>
+      <RestrictionStatus Status="Close"/>
+    </AvailStatusMessage>
+    <AvailStatusMessage BookingLimit="0">
+      <StatusApplicationControl Start="2026-11-03" End="2026-11-03" InvTypeCode="SGL"/>
+    </AvailStatusMessage>
+    <AvailStatusMessage>
+      <StatusApplicationControl Start="2026-11-03" End="2026-11-03" InvTypeCode="SGL"/>
+      <RestrictionStatus Status="Close" Restriction="Arrival"/>
+    </AvailStatusMessage>
+  </AvailStatusMessages>
+</OTA_HotelAvailNotifRQ>`
+
+const invCountNotif = `<OTA_HotelInvCountNotifRQ xmlns="http://www.opentravel.org/OTA/2003/05">
+  <Inventories HotelCode="BER1">
+    <Inventory>
+      <StatusApplicationControl Start="2026-11-01" End="2026-11-02" InvTypeCode="SGL"/>
+      <InvCounts><InvCount CountType="2" Count="3"/></InvCounts>
+    </Inventory>
+  </Inventories>
+</OTA_HotelInvCountNotifRQ>`
+
+func TestParseHotelAvailNotif(t *testing.T) {
+	records, err := Parse(strings.NewReader(availNotif))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nov := func(day int) time.Time { return time.Date(2026, 11, day, 0, 0, 0, 0, time.UTC) }
+	expected := []availability.RangeRecord{
+		{Id: "BER1:DBL", From: nov(1), To: nov(8), Value: 1},
+		{Id: "BER1:DBL", From: nov(1), To: nov(2), Value: 0},
+		{Id: "BER1:DBL", From: nov(7), To: nov(8), Value: 0},
+		{Id: "BER1:SGL", From: nov(3), To: nov(4), Value: 0},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("there should be %d records, were %v", len(expected), records)
+	}
+	for i := range expected {
+		if records[i] != expected[i] {
+			t.Errorf("record %d should be %v, was %v", i, expected[i], records[i])
+		}
+	}
+}
+
+func TestApplyInvCountNotif(t *testing.T) {
+	avc := availability.NewAvailabilityCollection()
+
+	if err := Apply(avc, availability.Day, strings.NewReader(invCountNotif)); err != nil {
+		t.Fatal(err)
+	}
+
+	av := avc.FindAvailabilityById(ResourceId("BER1", "SGL"))
+	if av == nil {
+		t.Fatalf("the availability of BER1:SGL should have been created")
+	}
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	if d := av.Get(t1, t1.AddDate(0, 0, 3), availability.Day).Data.Bytes(); !bytes.Equal(d, []byte{1, 1, 0}) {
+		t.Errorf("nov 1 and 2 should be available, was %v", d)
+	}
+}
+
+func TestParseShouldOnlyUseRoomAvailability(t *testing.T) {
+	records, err := Parse(strings.NewReader(`<OTA_HotelInvCountNotifRQ>
+  <Inventories HotelCode="BER1">
+    <Inventory>
+      <StatusApplicationControl Start="2026-11-01" End="2026-11-02" InvTypeCode="SGL"/>
+      <InvCounts><InvCount CountType="2" Count="0"/><InvCount CountType="4" Count="5"/><InvCount CountType="6" Count="1"/></InvCounts>
+    </Inventory>
+    <Inventory>
+      <StatusApplicationControl Start="2026-11-01" End="2026-11-02" InvTypeCode="DBL"/>
+      <InvCounts><InvCount CountType="4" Count="5"/></InvCounts>
+    </Inventory>
+  </Inventories>
+</OTA_HotelInvCountNotifRQ>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Id != "BER1:SGL" || records[0].Value != 0 {
+		t.Errorf("only the sold out SGL should be recorded, records were %v", records)
+	}
+
+	records, err = Parse(strings.NewReader(`<OTA_HotelAvailNotifRQ>
+  <AvailStatusMessages HotelCode="BER1">
+    <AvailStatusMessage>
+      <StatusApplicationControl Start="2026-11-01" End="2026-11-07" InvTypeCode="DBL" RatePlanCode="BAR"/>
+      <RestrictionStatus Status="Close"/>
+    </AvailStatusMessage>
+  </AvailStatusMessages>
+</OTA_HotelAvailNotifRQ>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Errorf("closing a rate plan should not close the room type, records were %v", records)
+	}
+}
+
+func TestParseInvalidMessages(t *testing.T) {
+	for _, input := range []string{
+		`<OTA_HotelRatePlanNotifRQ/>`,
+		`<OTA_HotelAvailNotifRQ><AvailStatusMessages HotelCode="H"><AvailStatusMessage><StatusApplicationControl Start="2026-11-01" End="2026-10-01" InvTypeCode="DBL"/><RestrictionStatus Status="Open"/></AvailStatusMessage></AvailStatusMessages></OTA_HotelAvailNotifRQ>`,
+		`<OTA_HotelAvailNotifRQ><AvailStatusMessages HotelCode="H"><AvailStatusMessage><StatusApplicationControl Start="2026-11-01" End="2026-11-01"/><RestrictionStatus Status="Open"/></AvailStatusMessage></AvailStatusMessages></OTA_HotelAvailNotifRQ>`,
+		`<OTA_HotelAvailNotifRQ><AvailStatusMessages HotelCode="H"><AvailStatusMessage><StatusApplicationControl Start="2026-11-01" End="2026-11-01" InvTypeCode="DBL"/><RestrictionStatus Status="Maybe"/></AvailStatusMessage></AvailStatusMessages></OTA_HotelAvailNotifRQ>`,
+	} {
+		if _, err := Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("parsing %s should fail", input)
+		}
+	}
+}
+
+func TestGeneratedNotifShouldRoundTrip(t *testing.T) {
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	av := availability.NewAvailability(availability.Day)
+	av.Set(t1.AddDate(0, 0, 1), t1.AddDate(0, 0, 4), 1)
+	results := map[string]*availability.AvailabilityResult{"DBL": av.Get(t1, t1.AddDate(0, 0, 7), availability.Day)}
+
+	msg, err := NewHotelAvailNotif("BER1", results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	avc := availability.NewAvailabilityCollection()
+	if err := Apply(avc, availability.Day, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	result := avc.FindAvailabilityById("BER1:DBL").Get(t1, t1.AddDate(0, 0, 7), availability.Day)
+	if !bytes.Equal(result.Data.Bytes(), results["DBL"].Data.Bytes()) {
+		t.Errorf("the parsed availability should be %v, was %v", results["DBL"].Data.Bytes(), result.Data.Bytes())
+	}
+	if l := len(msg.AvailStatusMessages.Messages); l != 3 {
+		t.Errorf("there should be 3 status messages, were %d", l)
+	}
+}
+
+func TestNewHotelInvCountNotifShouldRequireDayResolution(t *testing.T) {
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	av := availability.NewAvailability(availability.Hour)
+
+	_, err := NewHotelInvCountNotif("BER1", map[string]*availability.AvailabilityResult{"DBL": av.Get(t1, t1.AddDate(0, 0, 1), availability.Hour)})
+
+	if err == nil {
+		t.Errorf("an hourly result should be rejected")
+	}
+}
+
+func TestNewHotelAvailRS(t *testing.T) {
+	t1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	dbl := availability.NewAvailability(availability.Day)
+	dbl.Set(t1, t1.AddDate(0, 0, 7), 1)
+	sgl := availability.NewAvailability(availability.Day)
+	sgl.Set(t1, t1.AddDate(0, 0, 2), 1)
+
+	rs := NewHotelAvailRS("BER1", map[string]*availability.AvailabilityResult{
+		"DBL": dbl.Get(t1, t1.AddDate(0, 0, 3), availability.Day),
+		"SGL": sgl.Get(t1, t1.AddDate(0, 0, 3), availability.Day),
+	})
+
+	if len(rs.RoomStays) != 1 || rs.RoomStays[0].RoomTypes[0].RoomTypeCode != "DBL" {
+		t.Fatalf("only DBL should be available, was %v", rs.RoomStays)
+	}
+	if span := rs.RoomStays[0].TimeSpan; span.Start != "2026-11-01" || span.End != "2026-11-04" {
+		t.Errorf("the time span should be the requested stay, was %v", span)
+	}
+}
+
+func TestImportedAvailabilitiesShouldBeServed(t *testing.T) {
+	avc := availability.NewAvailabilityCollection()
+	Apply(avc, availability.Day, strings.NewReader(invCountNotif))
+	ts := httptest.NewServer(server.NewServer(avc, availability.Day))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/availabilities/" + ResourceId("BER1", "SGL") + "?from=2026-11-01&to=2026-11-04")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status should be %d, was %d", http.StatusOK, resp.StatusCode)
+	}
+}
@@ -0,0 +1,132 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/advincze/travl/availability"
+)
+
+const defaultBatchSize = 1000
+
+// Follower keeps a local collection in sync with a Source. The local
+// collection should only be read, every write goes to the primary.
+type Follower struct {
+	mu         sync.Mutex
+	collection availability.AvailabilityCollection
+	sequence   uint64
+	BatchSize  int
+}
+
+// NewFollower returns a follower that resumes after sequence, 0 for a new
+// follower which bootstraps from a snapshot if the primary's log has been
+// trimmed already.
+func NewFollower(collection availability.AvailabilityCollection, sequence uint64) *Follower {
+	return &Follower{
+		collection: collection,
+		sequence:   sequence,
+		BatchSize:  defaultBatchSize,
+	}
+}
+
+// Sequence returns the sequence of the last applied change, to be stored for
+// resuming.
+func (f *Follower) Sequence() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sequence
+}
+
+// Sync applies all changes of source the follower has not seen yet.
+func (f *Follower) Sync(source Source) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for {
+		entries, err := source.Changes(f.sequence, f.BatchSize)
+		if err == ErrSnapshotRequired {
+			snapshot, err := source.Snapshot()
+			if err != nil {
+				return err
+			}
+			if err := f.bootstrap(snapshot); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := f.apply(entries); err != nil {
+			return err
+		}
+		if len(entries) < f.BatchSize || f.BatchSize <= 0 {
+			return nil
+		}
+	}
+}
+
+// Run tails source until ctx is done.
+func (f *Follower) Run(ctx context.Context, source Source) error {
+	for {
+		if err := f.Sync(source); err != nil {
+			return err
+		}
+		if err := source.Wait(ctx, f.Sequence()); err != nil {
+			return err
+		}
+	}
+}
+
+func (f *Follower) apply(entries []Entry) error {
+	for _, entry := range entries {
+		if entry.Sequence <= f.sequence {
+			continue
+		}
+		if entry.Sequence != f.sequence+1 {
+			return ErrGap
+		}
+		switch entry.Kind {
+		case EntrySet:
+			av := f.collection.FindAvailabilityById(entry.Id)
+			if av == nil {
+				return ErrGap
+			}
+			av.Set(entry.From, entry.To, entry.Value)
+			f.collection.SaveAvailability(entry.Id, av)
+		case EntryPut:
+			av := new(availability.Availability)
+			if err := json.Unmarshal(entry.Data, av); err != nil {
+				return err
+			}
+			f.collection.SaveAvailability(entry.Id, av)
+		case EntryDelete:
+			f.collection.DeleteAvailability(entry.Id)
+		}
+		f.sequence = entry.Sequence
+	}
+	return nil
+}
+
+// bootstrap replaces the local collection with snapshot.
+func (f *Follower) bootstrap(snapshot *Snapshot) error {
+	avs := make(map[string]*availability.Availability, len(snapshot.Availabilities))
+	for id, data := range snapshot.Availabilities {
+		av := new(availability.Availability)
+		if err := json.Unmarshal(data, av); err != nil {
+			return err
+		}
+		avs[id] = av
+	}
+	ids, _ := f.collection.List("", "", 0)
+	for _, id := range ids {
+		if avs[id] == nil {
+			f.collection.DeleteAvailability(id)
+		}
+	}
+	for id, av := range avs {
+		f.collection.SaveAvailability(id, av)
+	}
+	f.sequence = snapshot.Sequence
+	return nil
+}
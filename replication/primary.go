@@ -0,0 +1,293 @@
+package replication
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/advincze/travl/availability"
+)
+
+var (
+	ErrSnapshotRequired = errors.New("replication: sequence is no longer in the change log, a snapshot is required")
+	ErrGap              = errors.New("replication: change log has a gap")
+)
+
+type EntryKind byte
+
+const (
+	EntrySet EntryKind = iota + 1
+	EntryPut
+	EntryDelete
+)
+
+// Entry is a single change of the primary. Set entries carry the range and
+// value, Put entries the whole availability as JSON.
+type Entry struct {
+	Sequence uint64          `json:"sequence"`
+	Kind     EntryKind       `json:"kind"`
+	Id       string          `json:"id"`
+	From     time.Time       `json:"from,omitempty"`
+	To       time.Time       `json:"to,omitempty"`
+	Value    byte            `json:"value,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+type Snapshot struct {
+	Sequence       uint64                     `json:"sequence"`
+	Availabilities map[string]json.RawMessage `json:"availabilities"`
+}
+
+// Source is what a follower tails, usually a Primary.
+type Source interface {
+	Changes(after uint64, limit int) ([]Entry, error)
+	Snapshot() (*Snapshot, error)
+	// Wait blocks until there are changes after the given sequence.
+	Wait(ctx context.Context, after uint64) error
+}
+
+// Primary wraps the collection all writes go to and records every change in
+// an ordered log keeping at least the last retain entries. The log is kept in
+// memory only, its sequences start at the time the primary was created, so a
+// follower of an earlier primary, as well as a new follower, bootstraps from
+// a snapshot.
+type Primary struct {
+	mu         sync.Mutex
+	collection availability.AvailabilityCollection
+	retain     int
+	log        []Entry
+	last       uint64
+	tracked    map[string]*availability.Availability
+	logged     map[string]*logged
+	removers   map[string]func()
+	notify     chan struct{}
+	err        error
+}
+
+// logged is the version and resource followers know of a tracked
+// availability.
+type logged struct {
+	version  int
+	resource []byte
+}
+
+func NewPrimary(collection availability.AvailabilityCollection, retain int) *Primary {
+	p := &Primary{
+		collection: collection,
+		retain:     retain,
+		tracked:    make(map[string]*availability.Availability),
+		logged:     make(map[string]*logged),
+		removers:   make(map[string]func()),
+		notify:     make(chan struct{}),
+		last:       uint64(time.Now().UnixNano()),
+	}
+	ids, _ := collection.List("", "", 0)
+	for _, id := range ids {
+		if av := collection.FindAvailabilityById(id); av != nil {
+			p.track(id, av)
+		}
+	}
+	return p
+}
+
+func (p *Primary) FindAvailabilityById(id string) *availability.Availability {
+	return p.collection.FindAvailabilityById(id)
+}
+
+func (p *Primary) SaveAvailability(id string, av *availability.Availability) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.collection.SaveAvailability(id, av)
+	p.setErr(p.put(id, av))
+}
+
+func (p *Primary) CompareAndSave(id string, av *availability.Availability, version int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.collection.CompareAndSave(id, av, version); err != nil {
+		return err
+	}
+	if err := p.put(id, av); err != nil {
+		p.setErr(err)
+		return err
+	}
+	return nil
+}
+
+func (p *Primary) DeleteAvailability(id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.collection.DeleteAvailability(id) {
+		return false
+	}
+	p.untrack(id)
+	p.append(Entry{Kind: EntryDelete, Id: id})
+	return true
+}
+
+func (p *Primary) List(prefix, cursor string, limit int) ([]string, string) {
+	return p.collection.List(prefix, cursor, limit)
+}
+
+func (p *Primary) Count() int {
+	return p.collection.Count()
+}
+
+// Sequence returns the sequence of the last change.
+func (p *Primary) Sequence() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.last
+}
+
+// Err returns the first error that occurred while logging a change.
+func (p *Primary) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Changes returns up to limit entries after the given sequence, or all of
+// them if limit is 0. A sequence before the log or after the last change,
+// which is one of an earlier primary, requires a snapshot.
+func (p *Primary) Changes(after uint64, limit int) ([]Entry, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	first := p.last - uint64(len(p.log)) + 1
+	if after+1 < first || after > p.last {
+		return nil, ErrSnapshotRequired
+	}
+	if after == p.last {
+		return nil, nil
+	}
+	entries := p.log[after+1-first:]
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return append([]Entry(nil), entries...), nil
+}
+
+func (p *Primary) Snapshot() (*Snapshot, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	snapshot := &Snapshot{
+		Sequence:       p.last,
+		Availabilities: make(map[string]json.RawMessage),
+	}
+	ids, _ := p.collection.List("", "", 0)
+	for _, id := range ids {
+		av := p.collection.FindAvailabilityById(id)
+		if av == nil {
+			continue
+		}
+		data, err := json.Marshal(av)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Availabilities[id] = data
+	}
+	return snapshot, nil
+}
+
+func (p *Primary) Wait(ctx context.Context, after uint64) error {
+	p.mu.Lock()
+	if p.last > after {
+		p.mu.Unlock()
+		return nil
+	}
+	notify := p.notify
+	p.mu.Unlock()
+	select {
+	case <-notify:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// put must be called with p.mu held. Saving a tracked availability again is
+// only logged if its resource or version changed, its Sets have been logged
+// as Set entries already. If the
+// change can not be logged, or the collection failed to save it, the log is
+// dropped so followers bootstrap from a snapshot.
+func (p *Primary) put(id string, av *availability.Availability) error {
+	if c, ok := p.collection.(interface{ Err() error }); ok {
+		if err := c.Err(); err != nil {
+			p.drop()
+			return err
+		}
+	}
+	if p.tracked[id] == av && !p.changed(id, av) {
+		return nil
+	}
+	data, err := json.Marshal(av)
+	if err != nil {
+		p.drop()
+		return err
+	}
+	p.untrack(id)
+	p.track(id, av)
+	p.append(Entry{Kind: EntryPut, Id: id, Data: data})
+	return nil
+}
+
+// changed reports whether the version or resource of the tracked av differs
+// from what has been logged.
+func (p *Primary) changed(id string, av *availability.Availability) bool {
+	resource, err := json.Marshal(av.Resource())
+	l := p.logged[id]
+	return err != nil || l.version != av.Version() || !bytes.Equal(l.resource, resource)
+}
+
+func (p *Primary) track(id string, av *availability.Availability) {
+	resource, _ := json.Marshal(av.Resource())
+	l := &logged{version: av.Version(), resource: resource}
+	p.tracked[id] = av
+	p.logged[id] = l
+	p.removers[id] = av.OnSet(func(av *availability.Availability, from, to time.Time, value byte) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		l.version = av.Version()
+		p.append(Entry{Kind: EntrySet, Id: id, From: from, To: to, Value: value})
+	})
+}
+
+func (p *Primary) untrack(id string) {
+	if remove := p.removers[id]; remove != nil {
+		remove()
+	}
+	delete(p.removers, id)
+	delete(p.tracked, id)
+	delete(p.logged, id)
+}
+
+// append must be called with p.mu held.
+func (p *Primary) append(entry Entry) {
+	p.last++
+	entry.Sequence = p.last
+	p.log = append(p.log, entry)
+	// trimmed in batches, so at most twice retain entries are kept
+	if p.retain > 0 && len(p.log) >= 2*p.retain {
+		p.log = append([]Entry(nil), p.log[len(p.log)-p.retain:]...)
+	}
+	close(p.notify)
+	p.notify = make(chan struct{})
+}
+
+// drop must be called with p.mu held. It skips a sequence, so followers at
+// the last one need a snapshot as well.
+func (p *Primary) drop() {
+	p.last++
+	p.log = nil
+	close(p.notify)
+	p.notify = make(chan struct{})
+}
+
+func (p *Primary) setErr(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
@@ -0,0 +1,237 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/advincze/travl/availability"
+)
+
+var t1 = time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+
+func assertInSync(t *testing.T, primary, follower availability.AvailabilityCollection) {
+	ids, _ := primary.List("", "", 0)
+	if follower.Count() != len(ids) {
+		t.Errorf("the follower should have %d availabilities, had %d", len(ids), follower.Count())
+	}
+	for _, id := range ids {
+		replica := follower.FindAvailabilityById(id)
+		if replica == nil {
+			t.Errorf("%s should be replicated", id)
+			continue
+		}
+		changes, err := availability.Diff(primary.FindAvailabilityById(id), replica, t1, t1.Add(10*24*time.Hour))
+		if err != nil || len(changes) != 0 {
+			t.Errorf("%s should be equal, changes were %v, %v", id, changes, err)
+		}
+	}
+}
+
+func TestFollowerShouldTailPrimary(t *testing.T) {
+	primary := NewPrimary(availability.NewAvailabilityCollection(), 100)
+	local := availability.NewAvailabilityCollection()
+	follower := NewFollower(local, 0)
+	follower.Sync(primary)
+	start := follower.Sequence()
+
+	//w
+	room12 := availability.NewAvailability(availability.Hour)
+	room12.Set(t1, t1.Add(48*time.Hour), 1)
+	primary.SaveAvailability("room-12", room12)
+	room12.Set(t1.Add(3*time.Hour), t1.Add(5*time.Hour), 0)
+	primary.SaveAvailability("room-12", room12)
+	primary.SaveAvailability("room-13", availability.NewAvailability(availability.Day))
+	primary.SaveAvailability("room-14", availability.NewAvailability(availability.Day))
+	primary.DeleteAvailability("room-14")
+	err := follower.Sync(primary)
+
+	//t
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := follower.Sequence(); s != start+5 || s != primary.Sequence() {
+		t.Errorf("the follower should be at sequence %d, was %d", primary.Sequence(), s)
+	}
+	assertInSync(t, primary, local)
+}
+
+func TestFollowerShouldResumeFromSequence(t *testing.T) {
+	primary := NewPrimary(availability.NewAvailabilityCollection(), 100)
+	local := availability.NewAvailabilityCollection()
+	room12 := availability.NewAvailability(availability.Hour)
+	primary.SaveAvailability("room-12", room12)
+	room12.Set(t1, t1.Add(time.Hour), 1)
+	follower := NewFollower(local, 0)
+	follower.Sync(primary)
+
+	//w the follower restarts with its stored sequence
+	room12.Set(t1.Add(2*time.Hour), t1.Add(4*time.Hour), 1)
+	resumed := NewFollower(local, follower.Sequence())
+	err := resumed.Sync(primary)
+
+	//t
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, expected := local.FindAvailabilityById("room-12").Version(), room12.Version(); v != expected {
+		t.Errorf("only the new change should be applied, version should be %d, was %d", expected, v)
+	}
+	assertInSync(t, primary, local)
+}
+
+func TestFollowerShouldBootstrapFromSnapshot(t *testing.T) {
+	primary := NewPrimary(availability.NewAvailabilityCollection(), 2)
+	room12 := availability.NewAvailability(availability.Hour)
+	primary.SaveAvailability("room-12", room12)
+	for i := 0; i < 10; i++ {
+		room12.SetAt(t1.Add(time.Duration(i)*time.Hour), 1)
+	}
+	local := availability.NewAvailabilityCollection()
+	local.SaveAvailability("stale", availability.NewAvailability(availability.Hour))
+
+	if _, err := primary.Changes(0, 0); err != ErrSnapshotRequired {
+		t.Fatalf("the error should be %v, was %v", ErrSnapshotRequired, err)
+	}
+
+	//w
+	follower := NewFollower(local, 0)
+	follower.BatchSize = 1
+	err := follower.Sync(primary)
+
+	//t
+	if err != nil {
+		t.Fatal(err)
+	}
+	if local.FindAvailabilityById("stale") != nil {
+		t.Errorf("the bootstrap should replace the local collection")
+	}
+	room12.SetAt(t1.Add(20*time.Hour), 1)
+	follower.Sync(primary)
+	assertInSync(t, primary, local)
+}
+
+func TestFollowerShouldBootstrapFromExistingData(t *testing.T) {
+	collection := availability.NewAvailabilityCollection()
+	room12 := availability.NewAvailability(availability.Hour)
+	room12.Set(t1, t1.Add(5*time.Hour), 1)
+	collection.SaveAvailability("room-12", room12)
+
+	//w
+	primary := NewPrimary(collection, 100)
+	local := availability.NewAvailabilityCollection()
+	err := NewFollower(local, 0).Sync(primary)
+
+	//t
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInSync(t, primary, local)
+}
+
+func TestFollowerShouldBootstrapAfterPrimaryRestart(t *testing.T) {
+	collection := availability.NewAvailabilityCollection()
+	primary := NewPrimary(collection, 100)
+	for _, id := range []string{"room-12", "room-13", "room-14"} {
+		primary.SaveAvailability(id, availability.NewAvailability(availability.Hour))
+	}
+	local := availability.NewAvailabilityCollection()
+	follower := NewFollower(local, 0)
+	follower.Sync(primary)
+
+	//w the primary restarts with an empty log
+	collection.DeleteAvailability("room-13")
+	restarted := NewPrimary(collection, 100)
+	restarted.SaveAvailability("room-15", availability.NewAvailability(availability.Hour))
+	resumed := NewFollower(local, follower.Sequence())
+	err := resumed.Sync(restarted)
+
+	//t
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := restarted.Changes(follower.Sequence(), 0); err != ErrSnapshotRequired {
+		t.Errorf("the error should be %v, was %v", ErrSnapshotRequired, err)
+	}
+	assertInSync(t, restarted, local)
+}
+
+func TestFollowerShouldReceiveResourceAndVersionChanges(t *testing.T) {
+	primary := NewPrimary(availability.NewAvailabilityCollection(), 100)
+	room12 := availability.NewAvailability(availability.Hour)
+	primary.SaveAvailability("room-12", room12)
+	local := availability.NewAvailabilityCollection()
+	follower := NewFollower(local, 0)
+	follower.Sync(primary)
+
+	//w
+	room12.SetResource(&availability.Resource{Id: "room-12", Type: "room", Group: "hotel-1"})
+	primary.SaveAvailability("room-12", room12)
+	primary.CompareAndSave("room-12", room12, room12.Version())
+	err := follower.Sync(primary)
+
+	//t
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica := local.FindAvailabilityById("room-12")
+	if r := replica.Resource(); r == nil || r.Group != "hotel-1" {
+		t.Errorf("the resource should be replicated, was %v", r)
+	}
+	if v := replica.Version(); v != room12.Version() {
+		t.Errorf("the version should be %d, was %d", room12.Version(), v)
+	}
+}
+
+type failingCollection struct {
+	*availability.MemAvailabilityCollection
+	err error
+}
+
+func (c *failingCollection) Err() error {
+	return c.err
+}
+
+func TestPrimaryShouldNotLogFailedSaves(t *testing.T) {
+	collection := &failingCollection{MemAvailabilityCollection: availability.NewAvailabilityCollection()}
+	primary := NewPrimary(collection, 100)
+	primary.SaveAvailability("room-12", availability.NewAvailability(availability.Hour))
+	sequence := primary.Sequence()
+
+	//w
+	collection.err = errors.New("disk full")
+	primary.SaveAvailability("room-13", availability.NewAvailability(availability.Hour))
+	err := primary.CompareAndSave("room-14", availability.NewAvailability(availability.Hour), 0)
+
+	//t
+	if err != collection.err || primary.Err() != collection.err {
+		t.Errorf("the error should be %v, was %v and %v", collection.err, err, primary.Err())
+	}
+	if _, err := primary.Changes(sequence, 0); err != ErrSnapshotRequired {
+		t.Errorf("followers should need a snapshot, the error was %v", err)
+	}
+}
+
+func TestFollowerRun(t *testing.T) {
+	primary := NewPrimary(availability.NewAvailabilityCollection(), 100)
+	local := availability.NewAvailabilityCollection()
+	follower := NewFollower(local, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- follower.Run(ctx, primary) }()
+
+	room12 := availability.NewAvailability(availability.Hour)
+	primary.SaveAvailability("room-12", room12)
+	room12.Set(t1, t1.Add(5*time.Hour), 1)
+	deadline := time.Now().Add(5 * time.Second)
+	for follower.Sequence() != primary.Sequence() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	if err := <-done; err != context.Canceled {
+		t.Errorf("run should stop with %v, was %v", context.Canceled, err)
+	}
+	assertInSync(t, primary, local)
+}
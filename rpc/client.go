@@ -0,0 +1,76 @@
+package rpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+type Client struct {
+	conn grpc.ClientConnInterface
+}
+
+type WatchStream struct {
+	stream grpc.ClientStream
+}
+
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{
+		conn: conn,
+	}
+}
+
+func (c *Client) Get(ctx context.Context, req *GetRequest) (*AvailabilityResult, error) {
+	resp := new(AvailabilityResult)
+	if err := c.invoke(ctx, "Get", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) Set(ctx context.Context, req *SetRequest) (*SetResponse, error) {
+	resp := new(SetResponse)
+	if err := c.invoke(ctx, "Set", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) Clear(ctx context.Context, req *ClearRequest) (*SetResponse, error) {
+	resp := new(SetResponse)
+	if err := c.invoke(ctx, "Clear", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Watch returns once the server has registered the watcher, so changes made
+// after Watch returns are guaranteed to be delivered.
+func (c *Client) Watch(ctx context.Context, req *WatchRequest) (*WatchStream, error) {
+	stream, err := c.conn.NewStream(ctx, &serviceDesc.Streams[0], "/"+serviceName+"/Watch")
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(req); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	if _, err := stream.Header(); err != nil {
+		return nil, err
+	}
+	return &WatchStream{stream: stream}, nil
+}
+
+func (w *WatchStream) Recv() (*ChangeNotification, error) {
+	n := new(ChangeNotification)
+	if err := w.stream.RecvMsg(n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (c *Client) invoke(ctx context.Context, method string, req, resp message) error {
+	return c.conn.Invoke(ctx, "/"+serviceName+"/"+method, req, resp)
+}
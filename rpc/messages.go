@@ -0,0 +1,424 @@
+package rpc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/advincze/travl/availability"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The message types below are encoded by hand following travl.proto. They
+// implement the legacy proto.Message and Marshaler interfaces, so gRPC sends
+// them with its default proto codec and clients generated from travl.proto
+// can talk to the server.
+
+type message interface {
+	marshal(b []byte) []byte
+	unmarshal(b []byte) error
+}
+
+// Range keeps the full wire value, so values above 1 are rejected rather
+// than truncated.
+type Range struct {
+	From  time.Time
+	To    time.Time
+	Value uint64
+}
+
+type GetRequest struct {
+	Id         string
+	From       time.Time
+	To         time.Time
+	Resolution availability.TimeResolution
+}
+
+type AvailabilityResult struct {
+	Resolution         availability.TimeResolution
+	InternalResolution availability.TimeResolution
+	From               time.Time
+	To                 time.Time
+	Length             int
+	Bits               []uint64
+}
+
+type SetRequest struct {
+	Id         string
+	Resolution availability.TimeResolution
+	Ranges     []Range
+}
+
+type ClearRequest struct {
+	Id   string
+	From time.Time
+	To   time.Time
+}
+
+type SetResponse struct {
+	Version int
+}
+
+type WatchRequest struct {
+	Id string
+}
+
+type ChangeNotification struct {
+	Id      string
+	Range   Range
+	Version int
+}
+
+func NewAvailabilityResult(result *availability.AvailabilityResult) *AvailabilityResult {
+	return &AvailabilityResult{
+		Resolution:         result.Resolution,
+		InternalResolution: result.InternalResolution,
+		From:               result.From,
+		To:                 result.To,
+		Length:             result.Data.Len(),
+		Bits:               result.Data.Words(),
+	}
+}
+
+func (r *AvailabilityResult) Result() *availability.AvailabilityResult {
+	result := availability.NewAvailabilityResult(r.Resolution, r.InternalResolution, availability.BitsetFromWords(r.Bits, r.Length), r.From)
+	result.InternalResolution = r.InternalResolution
+	return result
+}
+
+func (r *Range) Reset()                   { *r = Range{} }
+func (r *Range) String() string           { return fmt.Sprintf("%+v", *r) }
+func (*Range) ProtoMessage()              {}
+func (r *Range) Marshal() ([]byte, error) { return r.marshal(nil), nil }
+func (r *Range) Unmarshal(b []byte) error { return r.unmarshal(b) }
+
+func (r *GetRequest) Reset()                   { *r = GetRequest{} }
+func (r *GetRequest) String() string           { return fmt.Sprintf("%+v", *r) }
+func (*GetRequest) ProtoMessage()              {}
+func (r *GetRequest) Marshal() ([]byte, error) { return r.marshal(nil), nil }
+func (r *GetRequest) Unmarshal(b []byte) error { return r.unmarshal(b) }
+
+func (r *AvailabilityResult) Reset()                   { *r = AvailabilityResult{} }
+func (r *AvailabilityResult) String() string           { return fmt.Sprintf("%+v", *r) }
+func (*AvailabilityResult) ProtoMessage()              {}
+func (r *AvailabilityResult) Marshal() ([]byte, error) { return r.marshal(nil), nil }
+func (r *AvailabilityResult) Unmarshal(b []byte) error { return r.unmarshal(b) }
+
+func (r *SetRequest) Reset()                   { *r = SetRequest{} }
+func (r *SetRequest) String() string           { return fmt.Sprintf("%+v", *r) }
+func (*SetRequest) ProtoMessage()              {}
+func (r *SetRequest) Marshal() ([]byte, error) { return r.marshal(nil), nil }
+func (r *SetRequest) Unmarshal(b []byte) error { return r.unmarshal(b) }
+
+func (r *ClearRequest) Reset()                   { *r = ClearRequest{} }
+func (r *ClearRequest) String() string           { return fmt.Sprintf("%+v", *r) }
+func (*ClearRequest) ProtoMessage()              {}
+func (r *ClearRequest) Marshal() ([]byte, error) { return r.marshal(nil), nil }
+func (r *ClearRequest) Unmarshal(b []byte) error { return r.unmarshal(b) }
+
+func (r *SetResponse) Reset()                   { *r = SetResponse{} }
+func (r *SetResponse) String() string           { return fmt.Sprintf("%+v", *r) }
+func (*SetResponse) ProtoMessage()              {}
+func (r *SetResponse) Marshal() ([]byte, error) { return r.marshal(nil), nil }
+func (r *SetResponse) Unmarshal(b []byte) error { return r.unmarshal(b) }
+
+func (r *WatchRequest) Reset()                   { *r = WatchRequest{} }
+func (r *WatchRequest) String() string           { return fmt.Sprintf("%+v", *r) }
+func (*WatchRequest) ProtoMessage()              {}
+func (r *WatchRequest) Marshal() ([]byte, error) { return r.marshal(nil), nil }
+func (r *WatchRequest) Unmarshal(b []byte) error { return r.unmarshal(b) }
+
+func (r *ChangeNotification) Reset()                   { *r = ChangeNotification{} }
+func (r *ChangeNotification) String() string           { return fmt.Sprintf("%+v", *r) }
+func (*ChangeNotification) ProtoMessage()              {}
+func (r *ChangeNotification) Marshal() ([]byte, error) { return r.marshal(nil), nil }
+func (r *ChangeNotification) Unmarshal(b []byte) error { return r.unmarshal(b) }
+
+func (r *Range) marshal(b []byte) []byte {
+	b = appendTime(b, 1, r.From)
+	b = appendTime(b, 2, r.To)
+	return appendVarint(b, 3, r.Value)
+}
+
+func (r *Range) unmarshal(b []byte) error {
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, b []byte) int {
+		switch num {
+		case 1:
+			return consumeTime(typ, b, &r.From)
+		case 2:
+			return consumeTime(typ, b, &r.To)
+		case 3:
+			return consumeVarint(typ, b, func(v uint64) { r.Value = v })
+		}
+		return 0
+	})
+}
+
+func (r *GetRequest) marshal(b []byte) []byte {
+	b = appendString(b, 1, r.Id)
+	b = appendTime(b, 2, r.From)
+	b = appendTime(b, 3, r.To)
+	return appendVarint(b, 4, uint64(r.Resolution))
+}
+
+func (r *GetRequest) unmarshal(b []byte) error {
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, b []byte) int {
+		switch num {
+		case 1:
+			return consumeString(typ, b, &r.Id)
+		case 2:
+			return consumeTime(typ, b, &r.From)
+		case 3:
+			return consumeTime(typ, b, &r.To)
+		case 4:
+			return consumeVarint(typ, b, func(v uint64) { r.Resolution = availability.TimeResolution(v) })
+		}
+		return 0
+	})
+}
+
+func (r *AvailabilityResult) marshal(b []byte) []byte {
+	b = appendVarint(b, 1, uint64(r.Resolution))
+	b = appendVarint(b, 2, uint64(r.InternalResolution))
+	b = appendTime(b, 3, r.From)
+	b = appendTime(b, 4, r.To)
+	b = appendVarint(b, 5, uint64(r.Length))
+	if len(r.Bits) > 0 {
+		b = protowire.AppendTag(b, 6, protowire.BytesType)
+		b = protowire.AppendVarint(b, uint64(len(r.Bits)*8))
+		for _, w := range r.Bits {
+			b = protowire.AppendFixed64(b, w)
+		}
+	}
+	return b
+}
+
+func (r *AvailabilityResult) unmarshal(b []byte) error {
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, b []byte) int {
+		switch num {
+		case 1:
+			return consumeVarint(typ, b, func(v uint64) { r.Resolution = availability.TimeResolution(v) })
+		case 2:
+			return consumeVarint(typ, b, func(v uint64) { r.InternalResolution = availability.TimeResolution(v) })
+		case 3:
+			return consumeTime(typ, b, &r.From)
+		case 4:
+			return consumeTime(typ, b, &r.To)
+		case 5:
+			return consumeVarint(typ, b, func(v uint64) { r.Length = int(v) })
+		case 6:
+			return consumeFixed64s(typ, b, &r.Bits)
+		}
+		return 0
+	})
+}
+
+func (r *SetRequest) marshal(b []byte) []byte {
+	b = appendString(b, 1, r.Id)
+	b = appendVarint(b, 2, uint64(r.Resolution))
+	for i := range r.Ranges {
+		b = appendMessage(b, 3, &r.Ranges[i])
+	}
+	return b
+}
+
+func (r *SetRequest) unmarshal(b []byte) error {
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, b []byte) int {
+		switch num {
+		case 1:
+			return consumeString(typ, b, &r.Id)
+		case 2:
+			return consumeVarint(typ, b, func(v uint64) { r.Resolution = availability.TimeResolution(v) })
+		case 3:
+			var rng Range
+			n := consumeMessage(typ, b, &rng)
+			if n > 0 {
+				r.Ranges = append(r.Ranges, rng)
+			}
+			return n
+		}
+		return 0
+	})
+}
+
+func (r *ClearRequest) marshal(b []byte) []byte {
+	b = appendString(b, 1, r.Id)
+	b = appendTime(b, 2, r.From)
+	return appendTime(b, 3, r.To)
+}
+
+func (r *ClearRequest) unmarshal(b []byte) error {
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, b []byte) int {
+		switch num {
+		case 1:
+			return consumeString(typ, b, &r.Id)
+		case 2:
+			return consumeTime(typ, b, &r.From)
+		case 3:
+			return consumeTime(typ, b, &r.To)
+		}
+		return 0
+	})
+}
+
+func (r *SetResponse) marshal(b []byte) []byte {
+	return appendVarint(b, 1, uint64(r.Version))
+}
+
+func (r *SetResponse) unmarshal(b []byte) error {
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, b []byte) int {
+		if num == 1 {
+			return consumeVarint(typ, b, func(v uint64) { r.Version = int(v) })
+		}
+		return 0
+	})
+}
+
+func (r *WatchRequest) marshal(b []byte) []byte {
+	return appendString(b, 1, r.Id)
+}
+
+func (r *WatchRequest) unmarshal(b []byte) error {
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, b []byte) int {
+		if num == 1 {
+			return consumeString(typ, b, &r.Id)
+		}
+		return 0
+	})
+}
+
+func (r *ChangeNotification) marshal(b []byte) []byte {
+	b = appendString(b, 1, r.Id)
+	b = appendMessage(b, 2, &r.Range)
+	return appendVarint(b, 3, uint64(r.Version))
+}
+
+func (r *ChangeNotification) unmarshal(b []byte) error {
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, b []byte) int {
+		switch num {
+		case 1:
+			return consumeString(typ, b, &r.Id)
+		case 2:
+			return consumeMessage(typ, b, &r.Range)
+		case 3:
+			return consumeVarint(typ, b, func(v uint64) { r.Version = int(v) })
+		}
+		return 0
+	})
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// appendTime writes every time but the zero one, including the unix time 0.
+func appendTime(b []byte, num protowire.Number, t time.Time) []byte {
+	if t.IsZero() {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(t.Unix()))
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendMessage(b []byte, num protowire.Number, m message) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m.marshal(nil))
+}
+
+// unmarshalFields calls fn for every field in b; fn returns the number of
+// bytes it consumed, 0 to skip an unknown field or a negative parse error.
+func unmarshalFields(b []byte, fn func(num protowire.Number, typ protowire.Type, b []byte) int) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		n = fn(num, typ, b)
+		if n == 0 {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+func consumeVarint(typ protowire.Type, b []byte, set func(uint64)) int {
+	if typ != protowire.VarintType {
+		return 0
+	}
+	v, n := protowire.ConsumeVarint(b)
+	if n > 0 {
+		set(v)
+	}
+	return n
+}
+
+func consumeTime(typ protowire.Type, b []byte, t *time.Time) int {
+	return consumeVarint(typ, b, func(v uint64) { *t = time.Unix(int64(v), 0).UTC() })
+}
+
+func consumeString(typ protowire.Type, b []byte, s *string) int {
+	if typ != protowire.BytesType {
+		return 0
+	}
+	v, n := protowire.ConsumeString(b)
+	if n > 0 {
+		*s = v
+	}
+	return n
+}
+
+func consumeMessage(typ protowire.Type, b []byte, m message) int {
+	if typ != protowire.BytesType {
+		return 0
+	}
+	v, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return n
+	}
+	if err := m.unmarshal(v); err != nil {
+		return -1
+	}
+	return n
+}
+
+func consumeFixed64s(typ protowire.Type, b []byte, words *[]uint64) int {
+	switch typ {
+	case protowire.Fixed64Type:
+		v, n := protowire.ConsumeFixed64(b)
+		if n > 0 {
+			*words = append(*words, v)
+		}
+		return n
+	case protowire.BytesType:
+		packed, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return n
+		}
+		for len(packed) > 0 {
+			v, m := protowire.ConsumeFixed64(packed)
+			if m < 0 {
+				return m
+			}
+			*words = append(*words, v)
+			packed = packed[m:]
+		}
+		return n
+	}
+	return 0
+}
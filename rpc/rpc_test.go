@@ -0,0 +1,235 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/advincze/travl/availability"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func newTestClient(t *testing.T) *Client {
+	listener := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	NewServer(availability.NewAvailabilityCollection(), availability.Hour).Register(gs)
+	go gs.Serve(listener)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewClient(conn)
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	t1 := time.Date(1982, 2, 7, 0, 0, 0, 0, time.UTC)
+	in := &SetRequest{
+		Id:         "room-12",
+		Resolution: availability.Minute5,
+		Ranges:     []Range{{From: t1, To: t1.Add(time.Hour), Value: 1}, {From: t1, To: t1.Add(time.Minute)}},
+	}
+
+	out := new(SetRequest)
+	if err := out.unmarshal(in.marshal(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.Id != in.Id || out.Resolution != in.Resolution || len(out.Ranges) != 2 || out.Ranges[0] != in.Ranges[0] || out.Ranges[1] != in.Ranges[1] {
+		t.Errorf("message should be %v, was %v", in, out)
+	}
+}
+
+func TestMessageShouldKeepUnixTimeZero(t *testing.T) {
+	epoch := time.Unix(0, 0).UTC()
+	in := &Range{From: epoch, To: epoch.Add(time.Hour)}
+
+	out := new(Range)
+	if err := out.unmarshal(in.marshal(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if !out.From.Equal(epoch) || out.From.IsZero() {
+		t.Errorf("from should be %v, was %v", epoch, out.From)
+	}
+}
+
+// descriptors of travl.proto, as a client generated from it would use them
+func travlProtoFile(t *testing.T) protoreflect.FileDescriptor {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, label descriptorpb.FieldDescriptorProto_Label, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(number), Type: typ.Enum(), Label: label.Enum()}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	optional, repeated := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	int64Type, stringType := descriptorpb.FieldDescriptorProto_TYPE_INT64, descriptorpb.FieldDescriptorProto_TYPE_STRING
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("travl.proto"),
+		Package: proto.String("travl"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Range"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("from", 1, int64Type, optional, ""),
+				field("to", 2, int64Type, optional, ""),
+				field("value", 3, descriptorpb.FieldDescriptorProto_TYPE_UINT32, optional, ""),
+			}},
+			{Name: proto.String("SetRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("id", 1, stringType, optional, ""),
+				field("resolution", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, optional, ""),
+				field("ranges", 3, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, repeated, ".travl.Range"),
+			}},
+			{Name: proto.String("SetResponse"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("version", 1, int64Type, optional, ""),
+			}},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestProtoClientShouldTalkToServer(t *testing.T) {
+	client := newTestClient(t)
+	messages := travlProtoFile(t).Messages()
+	t1 := time.Date(2014, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	rng := dynamicpb.NewMessage(messages.ByName("Range"))
+	rng.Set(rng.Descriptor().Fields().ByName("from"), protoreflect.ValueOfInt64(t1.Unix()))
+	rng.Set(rng.Descriptor().Fields().ByName("to"), protoreflect.ValueOfInt64(t1.Add(time.Hour).Unix()))
+	rng.Set(rng.Descriptor().Fields().ByName("value"), protoreflect.ValueOfUint32(1))
+	req := dynamicpb.NewMessage(messages.ByName("SetRequest"))
+	req.Set(req.Descriptor().Fields().ByName("id"), protoreflect.ValueOfString("room-12"))
+	ranges := req.Mutable(req.Descriptor().Fields().ByName("ranges")).List()
+	ranges.Append(protoreflect.ValueOfMessage(rng))
+	resp := dynamicpb.NewMessage(messages.ByName("SetResponse"))
+
+	//w the default proto codec is used
+	err := client.conn.Invoke(context.Background(), "/"+serviceName+"/Set", req, resp)
+
+	//t
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := resp.Get(resp.Descriptor().Fields().ByName("version")).Int(); v != 1 {
+		t.Errorf("version should be 1, was %d", v)
+	}
+	result, err := client.Get(context.Background(), &GetRequest{Id: "room-12", From: t1, To: t1.Add(2 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := result.Result().Count(); c != 1 {
+		t.Errorf("1 hour should be available, %d were", c)
+	}
+}
+
+func TestSetThenGet(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	t1 := time.Date(2014, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	resp, err := client.Set(ctx, &SetRequest{Id: "room-12", Ranges: []Range{{From: t1.Add(9 * time.Hour), To: t1.Add(17 * time.Hour), Value: 1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Version != 1 {
+		t.Errorf("version should be 1, was %d", resp.Version)
+	}
+
+	result, err := client.Get(ctx, &GetRequest{Id: "room-12", From: t1, To: t1.Add(72 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	av := result.Result()
+	if l, c := av.Data.Len(), av.Count(); l != 72 || c != 8 {
+		t.Errorf("8 of 72 hours should be available, %d of %d were", c, l)
+	}
+	if !av.From.Equal(t1) || av.Resolution != availability.Hour {
+		t.Errorf("the result should start at %v with resolution hour, was %v", t1, av)
+	}
+}
+
+func TestGetUnknownIdShouldReturnNotFound(t *testing.T) {
+	client := newTestClient(t)
+	t1 := time.Date(2014, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := client.Get(context.Background(), &GetRequest{Id: "room-12", From: t1, To: t1.Add(time.Hour)})
+
+	if c := status.Code(err); c != codes.NotFound {
+		t.Errorf("code should be %v, was %v", codes.NotFound, c)
+	}
+}
+
+func TestInvalidRequestsShouldBeRejected(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	t1 := time.Date(2014, 3, 1, 0, 0, 0, 0, time.UTC)
+	far := time.Date(1800, 1, 1, 0, 0, 0, 0, time.UTC)
+	client.Set(ctx, &SetRequest{Id: "room-12", Ranges: []Range{{From: t1, To: t1.Add(time.Hour), Value: 1}}})
+
+	for name, call := range map[string]func() error{
+		"get": func() error {
+			_, err := client.Get(ctx, &GetRequest{Id: "room-12", From: far, To: t1, Resolution: availability.Minute})
+			return err
+		},
+		"set": func() error {
+			_, err := client.Set(ctx, &SetRequest{Id: "room-12", Ranges: []Range{{From: far, To: t1, Value: 1}}})
+			return err
+		},
+		"clear": func() error {
+			_, err := client.Clear(ctx, &ClearRequest{Id: "room-12", From: far, To: t1})
+			return err
+		},
+		"value": func() error {
+			_, err := client.Set(ctx, &SetRequest{Id: "room-12", Ranges: []Range{{From: t1, To: t1.Add(time.Hour), Value: 257}}})
+			return err
+		},
+	} {
+		if c := status.Code(call()); c != codes.InvalidArgument {
+			t.Errorf("the %s code should be %v, was %v", name, codes.InvalidArgument, c)
+		}
+	}
+}
+
+func TestWatchShouldStreamChanges(t *testing.T) {
+	client := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	t1 := time.Date(2014, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	stream, err := client.Watch(ctx, &WatchRequest{Id: "room-12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Set(ctx, &SetRequest{Id: "room-13", Ranges: []Range{{From: t1, To: t1.Add(time.Hour), Value: 1}}})
+	client.Set(ctx, &SetRequest{Id: "room-12", Ranges: []Range{{From: t1, To: t1.Add(time.Hour), Value: 1}}})
+	client.Clear(ctx, &ClearRequest{Id: "room-12", From: t1, To: t1.Add(time.Hour)})
+
+	for _, exp := range []uint64{1, 0} {
+		n, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n.Id != "room-12" || n.Range.Value != exp || !n.Range.From.Equal(t1) {
+			t.Errorf("notification should be for room-12 with value %d, was %v", exp, n)
+		}
+	}
+}
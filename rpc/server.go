@@ -0,0 +1,266 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/advincze/travl/availability"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	serviceName     = "travl.AvailabilityService"
+	watchBufferSize = 64
+	// maxUnits limits the units a single request reads or writes
+	maxUnits = 1 << 20
+)
+
+type Server struct {
+	mu         sync.Mutex
+	collection availability.AvailabilityCollection
+	defaultRes availability.TimeResolution
+	watchers   map[*watcher]bool
+}
+
+type watcher struct {
+	id string
+	ch chan *ChangeNotification
+}
+
+func NewServer(collection availability.AvailabilityCollection, defaultRes availability.TimeResolution) *Server {
+	return &Server{
+		collection: collection,
+		defaultRes: defaultRes,
+		watchers:   make(map[*watcher]bool),
+	}
+}
+
+func (s *Server) Register(gs *grpc.Server) {
+	gs.RegisterService(&serviceDesc, s)
+}
+
+func (s *Server) Get(ctx context.Context, req *GetRequest) (*AvailabilityResult, error) {
+	if err := validateRange(req.From, req.To); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	av := s.collection.FindAvailabilityById(req.Id)
+	if av == nil {
+		return nil, status.Errorf(codes.NotFound, "availability %q not found", req.Id)
+	}
+	res := req.Resolution
+	if res == 0 {
+		res = av.Resolution()
+	}
+	if !res.IsValid() {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid resolution %d", res)
+	}
+	// a coarser resolution is computed from the internal one
+	finest := res
+	if av.Resolution() < finest {
+		finest = av.Resolution()
+	}
+	if err := checkUnits(req.From, req.To, finest); err != nil {
+		return nil, err
+	}
+	return NewAvailabilityResult(av.Get(req.From, req.To, res)), nil
+}
+
+func (s *Server) Set(ctx context.Context, req *SetRequest) (*SetResponse, error) {
+	for _, rng := range req.Ranges {
+		if err := validateRange(rng.From, rng.To); err != nil {
+			return nil, err
+		}
+		if rng.Value > 1 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid value %d", rng.Value)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	av := s.collection.FindAvailabilityById(req.Id)
+	if av == nil {
+		res := req.Resolution
+		if res == 0 {
+			res = s.defaultRes
+		}
+		if !res.IsValid() {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid resolution %d", res)
+		}
+		av = availability.NewAvailability(res)
+	}
+	for _, rng := range req.Ranges {
+		if err := checkUnits(rng.From, rng.To, av.Resolution()); err != nil {
+			return nil, err
+		}
+	}
+	for _, rng := range req.Ranges {
+		av.Set(rng.From, rng.To, byte(rng.Value))
+		s.notify(&ChangeNotification{Id: req.Id, Range: rng, Version: av.Version()})
+	}
+	s.collection.SaveAvailability(req.Id, av)
+	return &SetResponse{Version: av.Version()}, nil
+}
+
+func (s *Server) Clear(ctx context.Context, req *ClearRequest) (*SetResponse, error) {
+	if err := validateRange(req.From, req.To); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	av := s.collection.FindAvailabilityById(req.Id)
+	if av == nil {
+		return nil, status.Errorf(codes.NotFound, "availability %q not found", req.Id)
+	}
+	if err := checkUnits(req.From, req.To, av.Resolution()); err != nil {
+		return nil, err
+	}
+	av.Set(req.From, req.To, 0)
+	s.collection.SaveAvailability(req.Id, av)
+	s.notify(&ChangeNotification{Id: req.Id, Range: Range{From: req.From, To: req.To}, Version: av.Version()})
+	return &SetResponse{Version: av.Version()}, nil
+}
+
+func (s *Server) Watch(req *WatchRequest, stream grpc.ServerStream) error {
+	w := &watcher{id: req.Id, ch: make(chan *ChangeNotification, watchBufferSize)}
+	s.mu.Lock()
+	s.watchers[w] = true
+	s.mu.Unlock()
+	defer s.unsubscribe(w)
+
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case n, ok := <-w.ch:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "watcher fell behind")
+			}
+			if err := stream.SendMsg(n); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// notify must be called with s.mu held.
+func (s *Server) notify(n *ChangeNotification) {
+	for w := range s.watchers {
+		if w.id != "" && w.id != n.Id {
+			continue
+		}
+		select {
+		case w.ch <- n:
+		default:
+			delete(s.watchers, w)
+			close(w.ch)
+		}
+	}
+}
+
+func (s *Server) unsubscribe(w *watcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.watchers[w] {
+		delete(s.watchers, w)
+		close(w.ch)
+	}
+}
+
+func validateRange(from, to time.Time) error {
+	if from.IsZero() || to.IsZero() {
+		return status.Error(codes.InvalidArgument, "from and to are required")
+	}
+	if to.Before(from) {
+		return status.Error(codes.InvalidArgument, "to must not be before from")
+	}
+	return nil
+}
+
+func checkUnits(from, to time.Time, res availability.TimeResolution) error {
+	if (to.Unix()-from.Unix())/int64(res) > maxUnits {
+		return status.Errorf(codes.InvalidArgument, "range exceeds %d units of %v", maxUnits, res)
+	}
+	return nil
+}
+
+type service interface {
+	Get(context.Context, *GetRequest) (*AvailabilityResult, error)
+	Set(context.Context, *SetRequest) (*SetResponse, error)
+	Clear(context.Context, *ClearRequest) (*SetResponse, error)
+	Watch(*WatchRequest, grpc.ServerStream) error
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*service)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Get", Handler: getHandler},
+		{MethodName: "Set", Handler: setHandler},
+		{MethodName: "Clear", Handler: clearHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "Watch", Handler: watchHandler, ServerStreams: true},
+	},
+	Metadata: "travl.proto",
+}
+
+func getHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := new(GetRequest)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(service).Get(ctx, req)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/Get"}
+	return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(service).Get(ctx, req.(*GetRequest))
+	})
+}
+
+func setHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := new(SetRequest)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(service).Set(ctx, req)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/Set"}
+	return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(service).Set(ctx, req.(*SetRequest))
+	})
+}
+
+func clearHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := new(ClearRequest)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(service).Clear(ctx, req)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/Clear"}
+	return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(service).Clear(ctx, req.(*ClearRequest))
+	})
+}
+
+func watchHandler(srv interface{}, stream grpc.ServerStream) error {
+	req := new(WatchRequest)
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	return srv.(service).Watch(req, stream)
+}
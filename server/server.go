@@ -0,0 +1,309 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/advincze/travl/availability"
+)
+
+const (
+	pathPrefix = "/availabilities/"
+	// maxUnits limits the units a single request reads or writes
+	maxUnits = 1 << 20
+)
+
+type Server struct {
+	mu         sync.Mutex
+	collection availability.AvailabilityCollection
+	defaultRes availability.TimeResolution
+}
+
+type Range struct {
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	Value byte      `json:"value"`
+}
+
+type SetRequest struct {
+	Resolution string  `json:"resolution"`
+	Ranges     []Range `json:"ranges"`
+}
+
+type ListResponse struct {
+	Ids   []string `json:"ids"`
+	Count int      `json:"count"`
+	Next  string   `json:"next,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type httpError struct {
+	status  int
+	message string
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+func NewServer(collection availability.AvailabilityCollection, defaultRes availability.TimeResolution) *Server {
+	return &Server{
+		collection: collection,
+		defaultRes: defaultRes,
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, pathPrefix)
+	if !strings.HasPrefix(r.URL.Path, pathPrefix) || strings.Contains(id, "/") {
+		writeError(w, &httpError{http.StatusNotFound, "not found"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		err = s.list(w, r)
+	case id == "":
+		w.Header().Set("Allow", "GET")
+		err = &httpError{http.StatusMethodNotAllowed, "method not allowed"}
+	default:
+		err = s.serveAvailability(w, r, id)
+	}
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+func (s *Server) serveAvailability(w http.ResponseWriter, r *http.Request, id string) error {
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		err = s.get(w, r, id)
+	case http.MethodPut:
+		err = s.set(w, r, id, true)
+	case http.MethodPatch:
+		err = s.set(w, r, id, false)
+	case http.MethodDelete:
+		err = s.clear(w, r, id)
+	default:
+		w.Header().Set("Allow", "GET, PUT, PATCH, DELETE")
+		err = &httpError{http.StatusMethodNotAllowed, "method not allowed"}
+	}
+	return err
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	limit := 0
+	if param := query.Get("limit"); param != "" {
+		var err error
+		if limit, err = strconv.Atoi(param); err != nil || limit < 0 {
+			return badRequest("invalid limit %q", param)
+		}
+	}
+	ids, next := s.collection.List(query.Get("prefix"), query.Get("cursor"), limit)
+	if ids == nil {
+		ids = []string{}
+	}
+	writeJSON(w, http.StatusOK, ListResponse{Ids: ids, Count: s.collection.Count(), Next: next})
+	return nil
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request, id string) error {
+	av := s.collection.FindAvailabilityById(id)
+	if av == nil {
+		return notFound(id)
+	}
+	from, to, err := parseRange(r)
+	if err != nil {
+		return err
+	}
+	res := av.Resolution()
+	if param := r.URL.Query().Get("res"); param != "" {
+		if res = availability.ParseTimeResolution(param); !res.IsValid() {
+			return badRequest("invalid resolution %q", param)
+		}
+	}
+	// a coarser resolution is computed from the internal one
+	finest := res
+	if av.Resolution() < finest {
+		finest = av.Resolution()
+	}
+	if err := checkUnits(from, to, finest); err != nil {
+		return err
+	}
+	w.Header().Set("ETag", etag(av))
+	writeJSON(w, http.StatusOK, av.Get(from, to, res))
+	return nil
+}
+
+func (s *Server) set(w http.ResponseWriter, r *http.Request, id string, create bool) error {
+	var request SetRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return badRequest("invalid body: %v", err)
+	}
+	for _, rng := range request.Ranges {
+		if err := validateRange(rng.From, rng.To); err != nil {
+			return err
+		}
+		if rng.Value > 1 {
+			return badRequest("invalid value %d", rng.Value)
+		}
+	}
+
+	av := s.collection.FindAvailabilityById(id)
+	if av == nil && !create {
+		return notFound(id)
+	}
+	if err := checkIfMatch(r, av); err != nil {
+		return err
+	}
+	// the stored availability is changed in place, keeping its history and
+	// listeners, s.mu makes sure no other request changes it in between
+	version := 0
+	if av != nil {
+		version = av.Version()
+	} else {
+		res := s.defaultRes
+		if request.Resolution != "" {
+			if res = availability.ParseTimeResolution(request.Resolution); !res.IsValid() {
+				return badRequest("invalid resolution %q", request.Resolution)
+			}
+		}
+		av = availability.NewAvailability(res)
+	}
+	for _, rng := range request.Ranges {
+		if err := checkUnits(rng.From, rng.To, av.Resolution()); err != nil {
+			return err
+		}
+	}
+	for _, rng := range request.Ranges {
+		av.Set(rng.From, rng.To, rng.Value)
+	}
+	return s.save(w, id, av, version)
+}
+
+// clear sets a range to 0 or deletes the availability if no range is given.
+func (s *Server) clear(w http.ResponseWriter, r *http.Request, id string) error {
+	av := s.collection.FindAvailabilityById(id)
+	if av == nil {
+		return notFound(id)
+	}
+	if err := checkIfMatch(r, av); err != nil {
+		return err
+	}
+	query := r.URL.Query()
+	if query.Get("from") == "" && query.Get("to") == "" {
+		s.collection.DeleteAvailability(id)
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	from, to, err := parseRange(r)
+	if err != nil {
+		return err
+	}
+	if err := checkUnits(from, to, av.Resolution()); err != nil {
+		return err
+	}
+	version := av.Version()
+	av.Set(from, to, 0)
+	return s.save(w, id, av, version)
+}
+
+func (s *Server) save(w http.ResponseWriter, id string, av *availability.Availability, version int) error {
+	if err := s.collection.CompareAndSave(id, av, version); err != nil {
+		return err
+	}
+	w.Header().Set("ETag", etag(av))
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func etag(av *availability.Availability) string {
+	return strconv.Quote(strconv.Itoa(av.Version()))
+}
+
+// checkIfMatch compares the If-Match header, if any, with the ETag of av,
+// which is nil for an availability that does not exist.
+func checkIfMatch(r *http.Request, av *availability.Availability) error {
+	header := r.Header.Get("If-Match")
+	if header == "" || header == "*" && av != nil {
+		return nil
+	}
+	if av != nil {
+		for _, tag := range strings.Split(header, ",") {
+			if strings.TrimSpace(tag) == etag(av) {
+				return nil
+			}
+		}
+	}
+	return &httpError{http.StatusPreconditionFailed, availability.ErrVersionConflict.Error()}
+}
+
+func parseRange(r *http.Request) (time.Time, time.Time, error) {
+	query := r.URL.Query()
+	from, err := availability.ParseTime(query.Get("from"))
+	if err != nil {
+		return time.Time{}, time.Time{}, badRequest("invalid from: %v", err)
+	}
+	to, err := availability.ParseTime(query.Get("to"))
+	if err != nil {
+		return time.Time{}, time.Time{}, badRequest("invalid to: %v", err)
+	}
+	return from, to, validateRange(from, to)
+}
+
+func validateRange(from, to time.Time) error {
+	if from.IsZero() || to.IsZero() {
+		return badRequest("from and to are required")
+	}
+	if to.Before(from) {
+		return badRequest("to must not be before from")
+	}
+	return nil
+}
+
+func checkUnits(from, to time.Time, res availability.TimeResolution) error {
+	if (to.Unix()-from.Unix())/int64(res) > maxUnits {
+		return badRequest("range exceeds %d units of %v", maxUnits, res)
+	}
+	return nil
+}
+
+func notFound(id string) error {
+	return &httpError{http.StatusNotFound, fmt.Sprintf("availability %q not found", id)}
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &httpError{http.StatusBadRequest, fmt.Sprintf(format, args...)}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var he *httpError
+	if errors.As(err, &he) {
+		status = he.status
+	} else if errors.Is(err, availability.ErrVersionConflict) {
+		status = http.StatusPreconditionFailed
+	}
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,244 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/advincze/travl/availability"
+)
+
+type result struct {
+	Resolution string `json:"resolution"`
+	Available  []int  `json:"available"`
+}
+
+func newTestServer() *httptest.Server {
+	return httptest.NewServer(NewServer(availability.NewAvailabilityCollection(), availability.Hour))
+}
+
+func do(t *testing.T, method, url, body string) *http.Response {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestGetUnknownIdShouldReturnNotFound(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	resp := do(t, "GET", ts.URL+"/availabilities/room-12?from=2014-03-01&to=2014-03-02", "")
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status should be %d, was %d", http.StatusNotFound, resp.StatusCode)
+	}
+	var body errorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == "" {
+		t.Errorf("the response should contain an error message, %v", err)
+	}
+}
+
+func TestPutThenGet(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	resp := do(t, "PUT", ts.URL+"/availabilities/room-12", `{"ranges":[{"from":"2014-03-01T09:00:00Z","to":"2014-03-01T17:00:00Z","value":1}]}`)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("status should be %d, was %d", http.StatusNoContent, resp.StatusCode)
+	}
+
+	resp = do(t, "GET", ts.URL+"/availabilities/room-12?from=2014-03-01&to=2014-03-02&res=h", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status should be %d, was %d", http.StatusOK, resp.StatusCode)
+	}
+	var body result
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for _, v := range body.Available {
+		count += v
+	}
+	if l := len(body.Available); l != 24 || count != 8 {
+		t.Errorf("8 of 24 hours should be available, %d of %d were", count, l)
+	}
+}
+
+func TestPatchUnknownIdShouldReturnNotFound(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	resp := do(t, "PATCH", ts.URL+"/availabilities/room-12", `{"ranges":[]}`)
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status should be %d, was %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func TestDeleteShouldClearRange(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+	do(t, "PUT", ts.URL+"/availabilities/room-12", `{"ranges":[{"from":"2014-03-01T00:00:00Z","to":"2014-03-02T00:00:00Z","value":1}]}`)
+
+	resp := do(t, "DELETE", ts.URL+"/availabilities/room-12?from=2014-03-01T00:00:00Z&to=2014-03-01T12:00:00Z", "")
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("status should be %d, was %d", http.StatusNoContent, resp.StatusCode)
+	}
+
+	resp = do(t, "GET", ts.URL+"/availabilities/room-12?from=2014-03-01&to=2014-03-02&res=d", "")
+	var body result
+	json.NewDecoder(resp.Body).Decode(&body)
+	if len(body.Available) != 1 || body.Available[0] != 0 {
+		t.Errorf("the day should not be available, was %v", body.Available)
+	}
+}
+
+func TestBadRequests(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+	do(t, "PUT", ts.URL+"/availabilities/room-12", `{"ranges":[]}`)
+
+	for _, tc := range []struct{ method, path, body string }{
+		{"GET", "/availabilities/room-12?from=2014-03-01&to=2014-03-02&res=fortnight", ""},
+		{"GET", "/availabilities/room-12?from=yesterday&to=2014-03-02", ""},
+		{"GET", "/availabilities/room-12?from=2014-03-02&to=2014-03-01", ""},
+		{"PUT", "/availabilities/room-12", `{"ranges":[{"from":"2014-03-01T00:00:00Z","to":"2014-03-02T00:00:00Z","value":2}]}`},
+		{"PUT", "/availabilities/room-13", `{"resolution":"fortnight"}`},
+		{"PATCH", "/availabilities/room-12", `not json`},
+		{"GET", "/availabilities/room-12?from=1900-01-01&to=9999-12-31&res=min", ""},
+		{"GET", "/availabilities/room-12?from=1900-01-01&to=9999-12-31&res=day", ""},
+		{"PATCH", "/availabilities/room-12", `{"ranges":[{"from":"1900-01-01T00:00:00Z","to":"9999-12-31T00:00:00Z","value":1}]}`},
+		{"DELETE", "/availabilities/room-12?from=1900-01-01&to=9999-12-31", ""},
+	} {
+		resp := do(t, tc.method, ts.URL+tc.path, tc.body)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s %s should return %d, was %d", tc.method, tc.path, http.StatusBadRequest, resp.StatusCode)
+		}
+	}
+}
+
+func TestDeleteWithoutRangeShouldDeleteAvailability(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+	do(t, "PUT", ts.URL+"/availabilities/room-12", `{"ranges":[]}`)
+
+	resp := do(t, "DELETE", ts.URL+"/availabilities/room-12", "")
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("status should be %d, was %d", http.StatusNoContent, resp.StatusCode)
+	}
+
+	resp = do(t, "GET", ts.URL+"/availabilities/room-12?from=2014-03-01&to=2014-03-02", "")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status should be %d, was %d", http.StatusNotFound, resp.StatusCode)
+	}
+	resp = do(t, "DELETE", ts.URL+"/availabilities/room-12", "")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status should be %d, was %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func TestListShouldPaginate(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+	for _, id := range []string{"room-12", "room-13", "room-14", "suite-1"} {
+		do(t, "PUT", ts.URL+"/availabilities/"+id, `{"ranges":[]}`)
+	}
+
+	var ids []string
+	cursor := ""
+	for pages := 0; pages < 5; pages++ {
+		resp := do(t, "GET", ts.URL+"/availabilities/?prefix=room-&limit=2&cursor="+cursor, "")
+		var body ListResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Count != 4 {
+			t.Errorf("count should be 4, was %d", body.Count)
+		}
+		ids = append(ids, body.Ids...)
+		if cursor = body.Next; cursor == "" {
+			break
+		}
+	}
+
+	if strings.Join(ids, ",") != "room-12,room-13,room-14" {
+		t.Errorf("the ids should be room-12,room-13,room-14, were %v", ids)
+	}
+	resp := do(t, "GET", ts.URL+"/availabilities/?limit=-1", "")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status should be %d, was %d", http.StatusBadRequest, resp.StatusCode)
+	}
+}
+
+func TestIfMatchShouldRejectStaleVersions(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+	resp := do(t, "PUT", ts.URL+"/availabilities/room-12", `{"ranges":[{"from":"2014-03-01T00:00:00Z","to":"2014-03-02T00:00:00Z","value":1}]}`)
+	created := resp.Header.Get("ETag")
+
+	resp = do(t, "GET", ts.URL+"/availabilities/room-12?from=2014-03-01&to=2014-03-02", "")
+	if etag := resp.Header.Get("ETag"); etag != created || etag == "" {
+		t.Fatalf("the ETag should be %s, was %s", created, etag)
+	}
+
+	req, _ := http.NewRequest("PATCH", ts.URL+"/availabilities/room-12", strings.NewReader(`{"ranges":[{"from":"2014-03-01T00:00:00Z","to":"2014-03-01T12:00:00Z","value":0}]}`))
+	req.Header.Set("If-Match", created)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get("ETag") == created {
+		t.Fatalf("the first admin should win with a new ETag, status was %d", resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest("DELETE", ts.URL+"/availabilities/room-12?from=2014-03-01T12:00:00Z&to=2014-03-02T00:00:00Z", nil)
+	req.Header.Set("If-Match", created)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("status should be %d, was %d", http.StatusPreconditionFailed, resp.StatusCode)
+	}
+	resp = do(t, "GET", ts.URL+"/availabilities/room-12?from=2014-03-01&to=2014-03-02&res=h", "")
+	var body result
+	json.NewDecoder(resp.Body).Decode(&body)
+	if len(body.Available) != 24 || body.Available[23] != 1 {
+		t.Errorf("the stale change should not be applied, was %v", body.Available)
+	}
+}
+
+func TestEditsShouldKeepHistoryAndListeners(t *testing.T) {
+	collection := availability.NewAvailabilityCollection()
+	av := availability.NewAvailability(availability.Hour)
+	av.EnableHistory()
+	collection.SaveAvailability("room-12", av)
+	h := availability.NewHierarchy()
+	group, _ := h.AddGroup("deluxe", "", availability.Hour, availability.RollupAny)
+	h.Add("room-12", "deluxe", av)
+	ts := httptest.NewServer(NewServer(collection, availability.Hour))
+	defer ts.Close()
+
+	//w
+	do(t, "PATCH", ts.URL+"/availabilities/room-12", `{"ranges":[{"from":"2014-03-01T00:00:00Z","to":"2014-03-02T00:00:00Z","value":1}]}`)
+	do(t, "DELETE", ts.URL+"/availabilities/room-12?from=2014-03-01T00:00:00Z&to=2014-03-01T12:00:00Z", "")
+
+	//t
+	stored := collection.FindAvailabilityById("room-12")
+	if stored != av || len(stored.History()) != 2 || stored.AsOf(1) == nil {
+		t.Errorf("the history should have 2 revisions, had %d", len(stored.History()))
+	}
+	t1, _ := availability.ParseTime("2014-03-01")
+	if c := group.Get(t1, t1.Add(24*time.Hour), availability.Hour).Data.Count(); c != 12 {
+		t.Errorf("the group should follow the edits, 12 hours should be available, %d were", c)
+	}
+}